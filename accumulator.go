@@ -0,0 +1,90 @@
+package errors
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MultiError summarizes a batch of failures, Errors holds a sample
+// and Total the number of failures, including the discarded ones
+type MultiError struct {
+	Errors []error
+	Total  int
+}
+
+// Error format the output, joining all the sampled errors
+func (m *MultiError) Error() string {
+	msgs := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		msgs[i] = err.Error()
+	}
+
+	str := strconv.Itoa(m.Total) + " errors occurred"
+	if m.Total > len(m.Errors) {
+		str += ", showing first " + strconv.Itoa(len(m.Errors))
+	}
+
+	return str + ": " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the sampled errors, it allows errors.Is and errors.As
+// from the standard library to inspect them
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
+// Accumulator collects errors of long running jobs with bounded
+// memory, only the first Max errors are retained while the rest are
+// just counted. The zero value is ready to use and retains every error.
+// It is safe for concurrent use
+type Accumulator struct {
+	// Max is the number of errors retained, 0 means unlimited
+	Max int
+
+	mu    sync.Mutex
+	errs  []error
+	total int
+}
+
+// Add records err, nil errors are ignored
+func (a *Accumulator) Add(err error) {
+	if IsNil(err) {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.total++
+	if a.Max <= 0 || len(a.errs) < a.Max {
+		a.errs = append(a.errs, err)
+	}
+}
+
+// Len returns the number of errors added so far
+func (a *Accumulator) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.total
+}
+
+// Err returns a *MultiError summarizing the errors added so far,
+// nil is returned if there was none
+func (a *Accumulator) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.total == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(a.errs))
+	copy(errs, a.errs)
+
+	return &MultiError{
+		Errors: errs,
+		Total:  a.total,
+	}
+}
@@ -0,0 +1,87 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAccumulator(t *testing.T) {
+	tc := []struct {
+		name         string
+		max          int
+		add          int
+		expectTotal  int
+		expectSample int
+		expectMsg    string
+	}{
+		{
+			name: "no errors",
+			max:  2,
+			add:  0,
+		},
+		{
+			name:         "below the cap",
+			max:          3,
+			add:          2,
+			expectTotal:  2,
+			expectSample: 2,
+			expectMsg:    "2 errors occurred: row 0; row 1",
+		},
+		{
+			name:         "above the cap",
+			max:          2,
+			add:          1000,
+			expectTotal:  1000,
+			expectSample: 2,
+			expectMsg:    "1000 errors occurred, showing first 2: row 0; row 1",
+		},
+		{
+			name:         "unlimited",
+			max:          0,
+			add:          3,
+			expectTotal:  3,
+			expectSample: 3,
+			expectMsg:    "3 errors occurred: row 0; row 1; row 2",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			acc := Accumulator{Max: tt.max}
+			for i := 0; i < tt.add; i++ {
+				acc.Add(fmt.Errorf("row %d", i))
+				acc.Add(nil)
+			}
+
+			if total := acc.Len(); tt.expectTotal != total {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectTotal, total)
+			}
+
+			err := acc.Err()
+			if tt.add == 0 {
+				if err != nil {
+					t.Errorf("\nexpected: nil\n     got: %v", err)
+				}
+				return
+			}
+
+			multi, ok := err.(*MultiError)
+			if !ok {
+				t.Error("invalid error, should be of type errors.MultiError")
+				return
+			}
+
+			if tt.expectTotal != multi.Total {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectTotal, multi.Total)
+			}
+
+			if sample := len(multi.Errors); tt.expectSample != sample {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectSample, sample)
+			}
+
+			if msg := multi.Error(); tt.expectMsg != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+		})
+	}
+}
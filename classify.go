@@ -0,0 +1,65 @@
+package errors
+
+import (
+	"database/sql"
+	stderrors "errors"
+	"strings"
+)
+
+// Classify returns the kind of err, a kind already present in the chain
+// takes precedence, otherwise well known errors are recognized:
+//
+//	sql.ErrNoRows           NotExist
+//	unique constraint       Duplicated
+//
+// Constraint violations are driver specific, they are detected from the
+// msg of the most common drivers (PostgreSQL, MySQL and SQLite)
+func Classify(err error) Kind {
+	if k := KindOf(err); k != Unknown {
+		return k
+	}
+
+	switch {
+	case IsNil(err):
+	case stderrors.Is(err, sql.ErrNoRows):
+		return NotExist
+	case isConstraintViolation(err):
+		return Duplicated
+	}
+
+	return Unknown
+}
+
+// AutoClassify wraps err with the kind reported by Classify, err is
+// returned unchanged if it already has a kind or it isn't recognized
+func AutoClassify(err error) error {
+	if IsNil(err) {
+		return nil
+	}
+
+	k := Classify(err)
+	if k == Unknown || KindOf(err) == k {
+		return err
+	}
+
+	return E(err, k)
+}
+
+// constraintMsgs are the msgs used by the most common sql drivers to
+// report unique constraint violations
+var constraintMsgs = []string{
+	"duplicate key value violates unique constraint", // PostgreSQL
+	"duplicate entry",          // MySQL
+	"unique constraint failed", // SQLite
+}
+
+func isConstraintViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, c := range constraintMsgs {
+		if strings.Contains(msg, c) {
+			return true
+		}
+	}
+
+	return false
+}
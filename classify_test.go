@@ -0,0 +1,91 @@
+package errors
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tc := []struct {
+		name   string
+		err    error
+		expect Kind
+	}{
+		{
+			name:   "no error",
+			err:    nil,
+			expect: Unknown,
+		},
+		{
+			name:   "sql.ErrNoRows",
+			err:    sql.ErrNoRows,
+			expect: NotExist,
+		},
+		{
+			name:   "wrapped sql.ErrNoRows",
+			err:    fmt.Errorf("getting user: %w", sql.ErrNoRows),
+			expect: NotExist,
+		},
+		{
+			name:   "sql.ErrNoRows wrapped by errors.Error",
+			err:    E(sql.ErrNoRows, "getting user"),
+			expect: NotExist,
+		},
+		{
+			name:   "kind in the chain takes precedence",
+			err:    E(sql.ErrNoRows, "getting user", Internal),
+			expect: Internal,
+		},
+		{
+			name:   "postgres unique constraint",
+			err:    fmt.Errorf(`pq: duplicate key value violates unique constraint "users_email_key"`),
+			expect: Duplicated,
+		},
+		{
+			name:   "mysql unique constraint",
+			err:    fmt.Errorf("Error 1062: Duplicate entry 'bob' for key 'name'"),
+			expect: Duplicated,
+		},
+		{
+			name:   "sqlite unique constraint",
+			err:    fmt.Errorf("UNIQUE constraint failed: users.email"),
+			expect: Duplicated,
+		},
+		{
+			name:   "unrecognized",
+			err:    fmt.Errorf("some error"),
+			expect: Unknown,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestAutoClassify(t *testing.T) {
+	err := AutoClassify(fmt.Errorf("getting user: %w", sql.ErrNoRows))
+	if !IsKind(err, NotExist) {
+		t.Errorf("\nexpected: %s\n     got: %s", NotExist, KindOf(err))
+	}
+
+	errDummy := fmt.Errorf("some error")
+	if got := AutoClassify(errDummy); got != errDummy {
+		t.Errorf("\nexpected: %v\n     got: %v", errDummy, got)
+	}
+
+	errKind := E(sql.ErrNoRows, Internal)
+	if got := AutoClassify(errKind); got != errKind {
+		t.Errorf("\nexpected: %v\n     got: %v", errKind, got)
+	}
+
+	if got := AutoClassify(nil); got != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", got)
+	}
+}
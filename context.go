@@ -0,0 +1,59 @@
+package errors
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	contextKeysMu sync.RWMutex
+	contextKeys   = map[string]interface{}{}
+)
+
+// RegisterContextKey registers ctxKey so EContext copies its value
+// from the context into the metadata of the error under metaKey.
+// It is intended to be called during initialization
+func RegisterContextKey(metaKey string, ctxKey interface{}) {
+	contextKeysMu.Lock()
+	defer contextKeysMu.Unlock()
+
+	contextKeys[metaKey] = ctxKey
+}
+
+// EContext works like E, additionally the values of the keys
+// registered via RegisterContextKey are copied from ctx into the
+// metadata. Values provided explicitly as MetaData take precedence
+func EContext(ctx context.Context, err error, args ...interface{}) error {
+	e, ok := E(err, args...).(*Error)
+	if !ok {
+		return nil
+	}
+
+	contextKeysMu.RLock()
+	defer contextKeysMu.RUnlock()
+
+	meta := make(MetaData, len(e.Meta)+len(contextKeys))
+	for metaKey, ctxKey := range contextKeys {
+		if v := ctx.Value(ctxKey); v != nil {
+			meta[metaKey] = v
+		}
+	}
+
+	if len(meta) == 0 {
+		return e
+	}
+
+	// Make a copy, the metadata could be shared with the cause
+	for k, v := range e.Meta {
+		meta[k] = v
+	}
+	e.Meta = meta
+
+	return e
+}
+
+// ShouldRetry reports whether the operation that failed with err should
+// be retried, that is err IsTransient and ctx is not done yet
+func ShouldRetry(ctx context.Context, err error) bool {
+	return IsTransient(err) && ctx.Err() == nil
+}
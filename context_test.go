@@ -0,0 +1,104 @@
+package errors
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type contextKey string
+
+func TestEContext(t *testing.T) {
+	requestIDKey := contextKey("request_id")
+	RegisterContextKey("request_id", requestIDKey)
+	defer func() {
+		contextKeysMu.Lock()
+		delete(contextKeys, "request_id")
+		contextKeysMu.Unlock()
+	}()
+
+	ctx := context.WithValue(context.Background(), requestIDKey, "abc")
+	cause := E(New("foo"), MetaData{"user": "bob"})
+
+	tc := []struct {
+		name   string
+		ctx    context.Context
+		args   []interface{}
+		expect MetaData
+	}{
+		{
+			name:   "registered key",
+			ctx:    ctx,
+			args:   []interface{}{"getting user", NotExist},
+			expect: MetaData{"request_id": "abc"},
+		},
+		{
+			name:   "missing value",
+			ctx:    context.Background(),
+			args:   []interface{}{"getting user", NotExist},
+			expect: nil,
+		},
+		{
+			name:   "explicit metadata takes precedence",
+			ctx:    ctx,
+			args:   []interface{}{MetaData{"request_id": "xyz", "foo": "bar"}},
+			expect: MetaData{"request_id": "xyz", "foo": "bar"},
+		},
+		{
+			name:   "metadata of the cause",
+			ctx:    ctx,
+			args:   []interface{}{cause},
+			expect: MetaData{"request_id": "abc", "user": "bob"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := EContext(tt.ctx, New("foo"), tt.args...)
+			e, ok := err.(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if !reflect.DeepEqual(tt.expect, e.Meta) {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, e.Meta)
+			}
+		})
+	}
+
+	if _, ok := cause.(*Error).Meta["request_id"]; ok {
+		t.Error("the metadata of the cause should not be modified")
+	}
+
+	if err := EContext(ctx, nil); err != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", err)
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tc := []struct {
+		name   string
+		ctx    context.Context
+		err    error
+		expect bool
+	}{
+		{name: "live transient", ctx: context.Background(), err: E(New("foo"), Transient), expect: true},
+		{name: "live timeout", ctx: context.Background(), err: E(E(New("foo"), Timeout), "fetching"), expect: true},
+		{name: "live not transient", ctx: context.Background(), err: E(New("foo"), NotExist)},
+		{name: "live std error", ctx: context.Background(), err: New("foo")},
+		{name: "cancelled transient", ctx: cancelled, err: E(New("foo"), Transient)},
+		{name: "cancelled not transient", ctx: cancelled, err: E(New("foo"), Invalid)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldRetry(tt.ctx, tt.err); got != tt.expect {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, got)
+			}
+		})
+	}
+}
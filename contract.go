@@ -0,0 +1,52 @@
+package errors
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// contract lists the interfaces *Error is expected to implement, along
+// with the name reported by AssertContract when one is missing
+var contract = []struct {
+	name       string
+	implements func(err error) bool
+}{
+	{"json.Marshaler", func(err error) bool { _, ok := err.(json.Marshaler); return ok }},
+	{"gob.GobEncoder", func(err error) bool { _, ok := err.(gob.GobEncoder); return ok }},
+	{"gob.GobDecoder", func(err error) bool { _, ok := err.(gob.GobDecoder); return ok }},
+	{"fmt.Formatter", func(err error) bool { _, ok := err.(fmt.Formatter); return ok }},
+	{"Unwrap() []error", func(err error) bool { _, ok := err.(interface{ Unwrap() []error }); return ok }},
+	{"Cause() error", func(err error) bool { _, ok := err.(interface{ Cause() error }); return ok }},
+	{"Is(error) bool", func(err error) bool { _, ok := err.(interface{ Is(error) bool }); return ok }},
+	{"StatusCoder", func(err error) bool { _, ok := err.(StatusCoder); return ok }},
+	{"Kinder", func(err error) bool { _, ok := err.(Kinder); return ok }},
+}
+
+// AssertContract reports, as an Internal error, the interfaces expected
+// from *Error that err doesn't implement, nil is returned if it
+// implements all of them. It is a testing aid to catch a dropped
+// interface early:
+//
+//	if err := errors.AssertContract(myErr); err != nil {
+//		t.Fatal(err)
+//	}
+func AssertContract(err error) error {
+	if IsNil(err) {
+		return E(New("nil error"), "contract not satisfied", Internal)
+	}
+
+	var missing []string
+	for _, c := range contract {
+		if !c.implements(err) {
+			missing = append(missing, c.name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return E(Errorf("%T doesn't implement %s", err, strings.Join(missing, ", ")), "contract not satisfied", Internal)
+	}
+
+	return nil
+}
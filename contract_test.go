@@ -0,0 +1,46 @@
+package errors
+
+import "testing"
+
+func TestAssertContract(t *testing.T) {
+	tc := []struct {
+		name      string
+		err       error
+		expectMsg string
+	}{
+		{
+			name: "error",
+			err:  E(New("foo"), "user not found", NotExist),
+		},
+		{
+			name: "standalone",
+			err:  &Error{},
+		},
+		{
+			name: "foreign error",
+			err:  statusCoder{status: 404},
+			expectMsg: "contract not satisfied: errors.statusCoder doesn't implement json.Marshaler, gob.GobEncoder, " +
+				"gob.GobDecoder, fmt.Formatter, Unwrap() []error, Cause() error, Is(error) bool, Kinder",
+		},
+		{
+			name:      "nil",
+			expectMsg: "contract not satisfied: nil error",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AssertContract(tt.err)
+			if tt.expectMsg == "" {
+				if err != nil {
+					t.Errorf("\nexpected: nil\n     got: %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.expectMsg {
+				t.Errorf("\nexpected: %s\n     got: %v", tt.expectMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CaptureStack makes E record the stack of its caller, it is reported
+// by MarshalDebug. It is disabled by default since it is expensive
+var CaptureStack bool
+
+// maxStackDepth is the max number of frames recorded by E
+const maxStackDepth = 32
+
+// callers returns the program counters of the caller of E
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	// skip runtime.Callers, callers and E
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// frames renders the program counters as "function file:line"
+func frames(pcs []uintptr) []string {
+	var out []string
+	fs := runtime.CallersFrames(pcs)
+	for {
+		f, more := fs.Next()
+		out = append(out, f.Function+" "+f.File+":"+strconv.Itoa(f.Line))
+		if !more {
+			break
+		}
+	}
+
+	return out
+}
+
+// ELoc works like E, the cause is the first error of args, additionally
+// the file:line of its caller is stored in the metadata under the key
+// "source". It is a cheaper alternative to CaptureStack
+//
+//	return errors.ELoc(err, "user not found", errors.NotExist)
+func ELoc(args ...interface{}) error {
+	var cause error
+	rest := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && cause == nil {
+			cause = err
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	e, ok := E(cause, rest...).(*Error)
+	if !ok {
+		return nil
+	}
+
+	if _, file, line, ok := runtime.Caller(1); ok {
+		e = e.WithMeta(MetaData{"source": path.Base(file) + ":" + strconv.Itoa(line)})
+	}
+
+	return e
+}
+
+// MarshalDebug returns a verbose json representation of err, along
+// with the fields of MarshalJSON it includes every layer of the chain
+// under "chain" and, if CaptureStack was enabled when the error was
+// built, the stack of the innermost layer under "stack".
+//
+// It exposes internal details, it must never be used for responses to
+// untrusted clients
+func MarshalDebug(err error) ([]byte, error) {
+	if IsNil(err) {
+		return []byte("null"), nil
+	}
+
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{cause: err, Kind: KindOf(err)}
+	}
+
+	var chain []interface{}
+	var stack []uintptr
+	for cause := error(e); !IsNil(cause); {
+		layer, ok := cause.(*Error)
+		if !ok {
+			chain = append(chain, map[string]string{"msg": cause.Error()})
+			break
+		}
+
+		fields := []field{
+			{"kind", layer.Kind.name()},
+			{"msg", layer.s},
+		}
+		if len(layer.Meta) > 0 {
+			fields = append(fields, field{"detail", layer.Meta})
+		}
+
+		b, err := marshalObject(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, json.RawMessage(b))
+		if layer.stack != nil {
+			stack = layer.stack
+		}
+		cause = layer.cause
+	}
+
+	fields := []field{
+		{"type", e.Kind.String()},
+		{MessageKey, e.Msg()},
+		{"code", e.Kind},
+		{"chain", chain},
+	}
+	if len(e.Meta) > 0 {
+		fields = append([]field{{"detail", e.Meta}}, fields...)
+	}
+	if stack != nil {
+		fields = append(fields, field{"stack", frames(stack)})
+	}
+
+	return marshalObject(fields)
+}
+
+// PrettyColor makes Pretty use ANSI colors, it is disabled by default
+var PrettyColor bool
+
+// ANSI escape codes used by Pretty
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// Pretty renders the chain for humans reading in a terminal, every
+// layer is indented under the previous one along with its kind and
+// metadata, e.g.
+//
+//	[NotExist] user not found
+//	    user: bob
+//	  [IO] io error
+//	      host: db
+//	    network unreachable
+//
+// Server faults are rendered in red and client faults in yellow
+// when PrettyColor is enabled
+func (e *Error) Pretty() string {
+	paint := func(color, s string) string {
+		if !PrettyColor {
+			return s
+		}
+		return color + s + ansiReset
+	}
+
+	var b strings.Builder
+	var err error = e
+	for depth := 0; !IsNil(err); depth++ {
+		indent := strings.Repeat("  ", depth)
+
+		layer, ok := err.(*Error)
+		if !ok {
+			b.WriteString(indent + err.Error() + "\n")
+			break
+		}
+
+		color := ansiYellow
+		if layer.IsServerFault() {
+			color = ansiRed
+		}
+
+		b.WriteString(indent + paint(color, "["+layer.Kind.name()+"]"))
+		if layer.s != "" {
+			b.WriteString(" " + layer.s)
+		}
+		b.WriteString("\n")
+
+		for _, k := range layer.Meta.keys() {
+			fmt.Fprintf(&b, "%s    %s: %v\n", indent, paint(ansiCyan, k), layer.Meta[k])
+		}
+		err = layer.cause
+	}
+
+	return b.String()
+}
+
+// Logfmt renders the error as a single logfmt line, with the msg given
+// by Error, the op if any, the kind, code, status and the metadata of
+// the chain sorted by key, e.g.
+//
+//	msg="user not found: no rows" kind=NotExist code=5 status=404 request_id=abc
+//
+// Values containing spaces, control chars, quotes or '=' are quoted.
+// In metadata keys those chars are replaced by '_', keys left empty or
+// already written, e.g. "msg" or "status", are skipped
+func (e *Error) Logfmt() string {
+	var b strings.Builder
+	seen := map[string]bool{}
+	write := func(key string, value interface{}) {
+		if key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key + "=" + logfmtValue(fmt.Sprint(value)))
+	}
+
+	write("msg", e.Error())
+	if e.Op != "" {
+		write("op", e.Op)
+	}
+	write("kind", e.Kind.name())
+	write("code", int(e.Kind))
+	write("status", e.StatusCode())
+
+	meta := e.mergedMeta()
+	for _, k := range meta.keys() {
+		write(logfmtKey(k), meta[k])
+	}
+
+	return b.String()
+}
+
+// Summary returns only the top level facts of the error: its kind,
+// code, status and msg, given by Msg. The chain and the metadata are
+// left out, it is intended for high volume telemetry
+func (e *Error) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"kind":   e.Kind.name(),
+		"code":   int(e.Kind),
+		"status": e.StatusCode(),
+		"msg":    e.Msg(),
+	}
+}
+
+// Fingerprint returns a stable id of the kind of failure represented by
+// the error, e.g. to group alerts. It is a hash of the kind, op and msg
+// of every *Error layer of the chain and the type of the root cause.
+// The text of the root cause and the metadata are left out, since they
+// usually hold values that change on every occurrence, like ids
+func (e *Error) Fingerprint() string {
+	h := fnv.New64a()
+	for err := error(e); !IsNil(err); {
+		layer, ok := err.(*Error)
+		if !ok {
+			fmt.Fprintf(h, "%T\n", err)
+			break
+		}
+
+		fmt.Fprintf(h, "%s|%s|%s\n", layer.Kind.name(), layer.Op, layer.s)
+		err = layer.cause
+	}
+
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// SameGroup reports whether a and b have the same Fingerprint, errors
+// that aren't an *Error are fingerprinted as if they were wrapped by E.
+// Two nil errors are in the same group
+func SameGroup(a, b error) bool {
+	if IsNil(a) || IsNil(b) {
+		return IsNil(a) && IsNil(b)
+	}
+
+	return fingerprint(a) == fingerprint(b)
+}
+
+func fingerprint(err error) string {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{cause: err, Kind: KindOf(err)}
+	}
+
+	return e.Fingerprint()
+}
+
+// logfmtValue quotes s if it can't be used as is as a logfmt value
+func logfmtValue(s string) string {
+	if s == "" || strings.IndexFunc(s, logfmtInvalid) != -1 {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
+
+// logfmtKey replaces the chars of s that can't be part of a logfmt key
+func logfmtKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if logfmtInvalid(r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// logfmtInvalid reports whether r can't be used as is in a logfmt
+// key or value, i.e. spaces, control chars, quotes and '='
+func logfmtInvalid(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == 0x7f || r == utf8.RuneError
+}
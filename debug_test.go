@@ -0,0 +1,264 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMarshalDebug(t *testing.T) {
+	defer func(v bool) { CaptureStack = v }(CaptureStack)
+
+	tc := []struct {
+		name        string
+		stack       bool
+		expectStack bool
+	}{
+		{
+			name:        "without stack",
+			stack:       false,
+			expectStack: false,
+		},
+		{
+			name:        "with stack",
+			stack:       true,
+			expectStack: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			CaptureStack = tt.stack
+			errIO := E(New("network unreachable"), "io error", IO, MetaData{"host": "db"})
+			err := E(errIO, "user not found", NotExist)
+
+			b, jerr := MarshalDebug(err)
+			if jerr != nil {
+				t.Error(jerr)
+				return
+			}
+
+			var got struct {
+				Error string `json:"error"`
+				Chain []struct {
+					Kind   string   `json:"kind"`
+					Msg    string   `json:"msg"`
+					Detail MetaData `json:"detail"`
+				} `json:"chain"`
+				Stack []string `json:"stack"`
+			}
+			if jerr := json.Unmarshal(b, &got); jerr != nil {
+				t.Error(jerr)
+				return
+			}
+
+			if got.Error != "user not found" {
+				t.Errorf("\nexpected: %s\n     got: %s", "user not found", got.Error)
+			}
+
+			if len(got.Chain) != 3 {
+				t.Errorf("\nexpected: 3 layers\n     got: %s", b)
+				return
+			}
+
+			expect := []string{"[NotExist] user not found", "[IO] io error", "[] network unreachable"}
+			for i, layer := range got.Chain {
+				if str := "[" + layer.Kind + "] " + layer.Msg; expect[i] != str {
+					t.Errorf("\nexpected: %s\n     got: %s", expect[i], str)
+				}
+			}
+
+			if got.Chain[1].Detail["host"] != "db" {
+				t.Errorf("\nexpected: %s\n     got: %v", "db", got.Chain[1].Detail["host"])
+			}
+
+			if hasStack := len(got.Stack) > 0; tt.expectStack != hasStack {
+				t.Errorf("\nexpected stack: %t\n     got: %s", tt.expectStack, b)
+			}
+
+			if tt.expectStack && !strings.Contains(got.Stack[0], "TestMarshalDebug") {
+				t.Errorf("\nexpected the stack to start at the caller\n     got: %s", got.Stack[0])
+			}
+		})
+	}
+}
+
+func TestError_Pretty(t *testing.T) {
+	defer func(v bool) { PrettyColor = v }(PrettyColor)
+
+	errIO := E(New("network unreachable"), "io error", IO, MetaData{"port": 5432, "host": "db"})
+	err := E(errIO, "user not found", NotExist, MetaData{"user": "bob"}).(*Error)
+
+	PrettyColor = false
+	expect := `[NotExist] user not found
+    user: bob
+  [IO] io error
+      host: db
+      port: 5432
+    network unreachable
+`
+	got := err.Pretty()
+	if expect != got {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, got)
+	}
+
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("\nexpected no colors\n     got: %q", got)
+	}
+
+	PrettyColor = true
+	got = err.Pretty()
+	if !strings.Contains(got, ansiRed+"[IO]"+ansiReset) || !strings.Contains(got, ansiYellow+"[NotExist]"+ansiReset) {
+		t.Errorf("\nexpected colors\n     got: %q", got)
+	}
+}
+
+func TestLogfmt(t *testing.T) {
+	tc := []struct {
+		name   string
+		err    error
+		expect string
+	}{
+		{
+			name:   "quoted msg",
+			err:    E(New("no rows"), "user not found", NotExist, MetaData{"request_id": "abc"}),
+			expect: `msg="user not found: no rows" kind=NotExist code=5 status=404 request_id=abc`,
+		},
+		{
+			name: "op and quoted metadata",
+			err: E(
+				E(New("refused"), "dial", IO, MetaData{"host": "db 1", "port": 5432}),
+				Op("users.Get"), MetaData{"query": `name="bob"`, "empty": ""},
+			),
+			expect: `msg="users.Get: dial: refused" op=users.Get kind=IO code=3 status=500 ` +
+				`empty="" host="db 1" port=5432 query="name=\"bob\""`,
+		},
+		{
+			name: "invalid and reserved metadata keys",
+			err: E(New("no rows"), "lookup", NotExist, MetaData{
+				"user id": 42, "msg": "dup", "status": 200, "a=b": 1, "": "empty", " ": "space",
+			}),
+			expect: `msg="lookup: no rows" kind=NotExist code=5 status=404 _=space a_b=1 user_id=42`,
+		},
+		{
+			name:   "control chars are quoted",
+			err:    E(New("refused"), "dial", IO, MetaData{"host": "db\r1", "tab": "a\vb"}),
+			expect: `msg="dial: refused" kind=IO code=3 status=500 host="db\r1" tab="a\vb"`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.(*Error).Logfmt(); got != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	err := E(
+		E(New("no rows"), "query", IO, MetaData{"host": "db1"}),
+		"user not found", NotExist, MetaData{"user_id": 42},
+	).(*Error)
+
+	expect := map[string]interface{}{
+		"kind":   "NotExist",
+		"code":   5,
+		"status": 404,
+		"msg":    "user not found",
+	}
+
+	if got := err.Summary(); !reflect.DeepEqual(expect, got) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, got)
+	}
+}
+
+func TestSameGroup(t *testing.T) {
+	query := func(id int, meta MetaData) error {
+		return E(E(Errorf("no rows for id %d", id), "query", IO, Op("db.Query")), "user not found", NotExist, meta)
+	}
+
+	tc := []struct {
+		name   string
+		a, b   error
+		expect bool
+	}{
+		{
+			name:   "different ids and metadata",
+			a:      query(1, MetaData{"user_id": 1}),
+			b:      query(2, MetaData{"user_id": 2}),
+			expect: true,
+		},
+		{
+			name: "different kind",
+			a:    query(1, nil),
+			b:    E(E(Errorf("no rows for id %d", 1), "query", IO, Op("db.Query")), "user not found", Invalid),
+		},
+		{
+			name: "different msg",
+			a:    query(1, nil),
+			b:    E(E(Errorf("no rows for id %d", 1), "query", IO, Op("db.Query")), "order not found", NotExist),
+		},
+		{
+			name: "different root type",
+			a:    query(1, nil),
+			b:    E(E(&customErr{msg: "no rows"}, "query", IO, Op("db.Query")), "user not found", NotExist),
+		},
+		{
+			name:   "std errors of the same type",
+			a:      stderrors.New("foo"),
+			b:      stderrors.New("bar"),
+			expect: true,
+		},
+		{
+			name:   "both nil",
+			expect: true,
+		},
+		{
+			name: "one nil",
+			a:    query(1, nil),
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameGroup(tt.a, tt.b); got != tt.expect {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestELoc(t *testing.T) {
+	meta := MetaData{"user": "bob"}
+	_, _, line, _ := runtime.Caller(0)
+	err := ELoc(New("no rows"), "user not found", NotExist, meta)
+
+	e, ok := err.(*Error)
+	if !ok {
+		t.Error("invalid error, should be of type errors.Error")
+		return
+	}
+
+	expect := "debug_test.go:" + strconv.Itoa(line+1)
+	if got := e.Meta["source"]; got != expect {
+		t.Errorf("\nexpected: %s\n     got: %v", expect, got)
+	}
+
+	if e.Kind != NotExist || e.Meta["user"] != "bob" || e.Error() != "user not found: no rows" {
+		t.Errorf("\nexpected: the args of E applied\n     got: %s %v", e.DebugString(), e.Meta)
+	}
+
+	if _, ok := meta["source"]; ok {
+		t.Error("the metadata of the caller should be left untouched")
+	}
+
+	if err := ELoc("user not found", NotExist); err != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", err)
+	}
+}
@@ -1,10 +1,20 @@
 package errors
 
 import (
+	"bytes"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
 // MetaData is used to store aditional info about the underlaying error,
@@ -15,22 +25,57 @@ type MetaData map[string]interface{}
 // It contains a number of fields, each of different type.
 // An Error value may leave some values unset.
 type Error struct {
+	// Op is the operation being performed, usually the name of
+	// the method being invoked.
+	Op Op
 	// Kind is the class of error, such as permission failure,
 	// or "Other" if its class is unknown or irrelevant.
 	Kind Kind
 	// The underlying error that triggered this one, if any.
 	cause error
+	// Additional independent causes, see MultipleCauses
+	causes []error
 	// The msg to end user
 	s string
 	// Metadata about the underlaying error
 	Meta MetaData
+	// The program counters where the error was built, see CaptureStack
+	stack []uintptr
+	// Headers to be set by WriteHTTP, see WithHeader
+	header http.Header
+	// Whether the error was already logged, see MarkLogged
+	logged bool
+	// Whether s is used as is by Msg, see WithUserMessage
+	userMsg bool
 }
 
 var _ json.Marshaler = (*Error)(nil)
 
+// Zero is a valid zero value *Error, it has no cause, msg nor kind, so
+// its Error is an empty string. It is intended as a sentinel to compare
+// against by identity, e.g. as the initial value of an error variable
+// that must never be a nil *Error. Unlike other *Error without cause,
+// errors.Is(err, Zero) only matches Zero itself. It is shared, so it
+// must not be modified
+var Zero = &Error{Kind: Unknown}
+
+// Op describes an operation, usually as the package and method,
+// such as "users.Get". Ops are internal details, they are included in
+// the output of Error for logging but never in Msg or PublicError
+type Op string
+
 // Kind defines the kind of error this is, mostly for use by systems
 type Kind uint8
 
+// Kinder is implemented by errors that can advertise its own Kind,
+// it allows custom error types to participate in kind based routing
+// along with *Error
+type Kinder interface {
+	ErrorKind() Kind
+}
+
+var _ Kinder = (*Error)(nil)
+
 // Kinds of errors.
 //
 // The values of the error kinds are common between both
@@ -52,6 +97,7 @@ const (
 	Unsupported               // An unsupported media type.
 	NotAcceptable             // We cannot accept the provided media types.
 	Timeout                   // Operation timed out
+	Unimplemented             // Operation not implemented yet.
 )
 
 // String transforms enums into string, useful for encoders
@@ -85,16 +131,193 @@ func (k Kind) String() string {
 		return "not accepted"
 	case Timeout:
 		return "timed out"
+	case Unimplemented:
+		return "not implemented"
+	}
+
+	if c, ok := customKind(k); ok {
+		return c.name
 	}
 	return "unknown error kind"
 }
 
+// kindNames holds the kinds as they are named in code
+var kindNames = [...]string{
+	Unknown:       "Unknown",
+	Invalid:       "Invalid",
+	Permission:    "Permission",
+	IO:            "IO",
+	Duplicated:    "Duplicated",
+	NotExist:      "NotExist",
+	Private:       "Private",
+	Internal:      "Internal",
+	Decrypt:       "Decrypt",
+	Unmarshal:     "Unmarshal",
+	Transient:     "Transient",
+	Unsupported:   "Unsupported",
+	NotAcceptable: "NotAcceptable",
+	Timeout:       "Timeout",
+	Unimplemented: "Unimplemented",
+}
+
+// name returns the identifier of the kind, e.g. "NotExist"
+func (k Kind) name() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+
+	if c, ok := customKind(k); ok {
+		return c.name
+	}
+
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// firstCustomKind is the value of the first kind allocated by
+// RegisterKind, the lower values are reserved for the built-in kinds
+const firstCustomKind Kind = 64
+
+type customKindInfo struct {
+	name   string
+	status int
+}
+
+var (
+	customKindsMu sync.RWMutex
+	customKinds   []customKindInfo
+)
+
+// RegisterKind allocates a new kind above the built-in ones for domain
+// specific errors, e.g. QuotaExceeded. The name is used by String and
+// Code, and statusCode by StatusCode. It panics if there is no room for
+// more kinds. It is intended to be called during initialization.
+//
+// The value of a custom kind depends on the order of registration, so
+// it is only stable as long as every process registers the same kinds
+// in the same order. Keep it in mind before persisting it or sending it
+// to another service, e.g. via MarshalJSON or GobEncode
+func RegisterKind(name string, statusCode int) Kind {
+	customKindsMu.Lock()
+	defer customKindsMu.Unlock()
+
+	if int(firstCustomKind)+len(customKinds) > math.MaxUint8 {
+		panic("errors: too many custom kinds")
+	}
+
+	customKinds = append(customKinds, customKindInfo{name: name, status: statusCode})
+	return firstCustomKind + Kind(len(customKinds)-1)
+}
+
+// customKind returns the info recorded by RegisterKind for k
+func customKind(k Kind) (customKindInfo, bool) {
+	if k < firstCustomKind {
+		return customKindInfo{}, false
+	}
+
+	customKindsMu.RLock()
+	defer customKindsMu.RUnlock()
+
+	i := int(k - firstCustomKind)
+	if i >= len(customKinds) {
+		return customKindInfo{}, false
+	}
+
+	return customKinds[i], true
+}
+
+// CodePrefix is prepended to the output of Kind.Code, it allows each
+// service in a fleet to produce globally unique codes, e.g. "USERS"
+// turns NotExist into "USERS_NOT_EXIST". An empty prefix keeps the
+// bare code
+var CodePrefix string
+
+// Code returns a machine friendly token of the kind, e.g. "NOT_EXIST",
+// prefixed by CodePrefix if set
+func (k Kind) Code() string {
+	code := k.words('_', unicode.ToUpper)
+	if CodePrefix != "" {
+		return CodePrefix + "_" + code
+	}
+
+	return code
+}
+
+// words splits the name of the kind in words joined by sep, each rune
+// is transformed by fn, e.g. NotExist gives "NOT_EXIST" or "not-exist"
+func (k Kind) words(sep rune, fn func(rune) rune) string {
+	name := k.name()
+
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(rune(name[i-1])) {
+			b.WriteRune(sep)
+		}
+		b.WriteRune(fn(r))
+	}
+
+	return b.String()
+}
+
+// SeverityRank returns the severity of the kind as a number, the higher
+// the more severe, e.g. to sort errors by KindOf(err).SeverityRank().
+// From the lowest to the highest:
+//
+//	1 Invalid, Decrypt, Unmarshal, Unsupported, NotAcceptable
+//	2 NotExist, Duplicated
+//	3 Permission, Private
+//	4 Transient, Timeout
+//	5 Unimplemented
+//	6 IO
+//	7 Unknown
+//	8 Internal
+//
+// Custom kinds registered via RegisterKind rank 0
+func (k Kind) SeverityRank() int {
+	switch k {
+	case Invalid,
+		Decrypt,
+		Unmarshal,
+		Unsupported,
+		NotAcceptable:
+		return 1
+	case NotExist,
+		Duplicated:
+		return 2
+	case Permission,
+		Private:
+		return 3
+	case Transient,
+		Timeout:
+		return 4
+	case Unimplemented:
+		return 5
+	case IO:
+		return 6
+	case Unknown:
+		return 7
+	case Internal:
+		return 8
+	}
+
+	return 0
+}
+
+// UnmarshalUnprocessable makes Unmarshal map to
+// http.StatusUnprocessableEntity instead of http.StatusBadRequest, to
+// distinguish malformed input from invalid operations. It is disabled by
+// default to preserve the original mapping
+var UnmarshalUnprocessable bool
+
 // StatusCode transform kind to http.StatusCode
 func (k Kind) StatusCode() int {
 	switch k {
+	case Unmarshal:
+		if UnmarshalUnprocessable {
+			return http.StatusUnprocessableEntity
+		}
+		return http.StatusBadRequest
 	case Invalid,
-		Decrypt,
-		Unmarshal:
+		Decrypt:
 		return http.StatusBadRequest
 	case Permission,
 		Private:
@@ -111,36 +334,65 @@ func (k Kind) StatusCode() int {
 		return http.StatusConflict
 	case Timeout:
 		return http.StatusRequestTimeout
+	case Unimplemented:
+		return http.StatusNotImplemented
 	case Unknown:
 	case Internal:
 	case IO:
 	}
 
+	if c, ok := customKind(k); ok {
+		return c.status
+	}
+
 	return http.StatusInternalServerError
 }
 
+// Title returns a short human title of the kind, as the reason phrase
+// of its StatusCode, e.g. "Not Found". It is safe to show to end users
+func (k Kind) Title() string {
+	return http.StatusText(k.StatusCode())
+}
+
+// MultipleCauses makes E keep every error argument as an independent
+// cause of the error, instead of only the last *Error one. Error joins
+// the msg of all of them and Unwrap exposes them for errors.Is.
+// It is disabled by default to preserve the original behavior of E
+var MultipleCauses bool
+
 // E builds an error value from its arguments.
 // There must be at least one argument.
 // The type of each argument determines its meaning.
 // If more than one argument of a given type is presented,
 // only the last one is recorded.
 //
-// If the error is nil, nil will be returned.
+// If the error is nil, nil will be returned. A typed nil error,
+// e.g. a nil *MyErr stored in an error, is considered nil as well.
 //
 // The types are:
 //	string
 //		The msg to help to trace error callers, the last msg used
 //		will be available via Msg method
+//	errors.Op
+//		The operation being performed, such as "users.Get".
 //	errors.Kind
 //		The class of error, such as permission failure.
 //	error
 //		The underlying error that triggered this one.
+//		An *Error argument replaces err as the cause, any other
+//		error argument is ignored unless MultipleCauses is enabled,
+//		in which case every error argument is kept as an additional
+//		cause.
+//	fmt.Stringer
+//		Its String is used as the msg, unless a string is given too,
+//		a plain string always takes precedence over a Stringer.
 //
 // If Kind is not specified or Unknown, we set it to the Kind of
 // the underlying error.
 // If MetaData is not defined, we use the underlaying MetaData
 func E(err error, args ...interface{}) error {
-	if err == nil {
+	// typed nil errors are treated as nil, they would panic later
+	if IsNil(err) {
 		return nil
 	}
 
@@ -148,10 +400,22 @@ func E(err error, args ...interface{}) error {
 		cause: err,
 	}
 
+	if CaptureStack {
+		e.stack = callers()
+	}
+
+	var (
+		hasMsg   bool
+		stringer fmt.Stringer
+	)
+
 	for _, arg := range args {
 		switch opt := arg.(type) {
 		case string:
 			e.s = opt
+			hasMsg = true
+		case Op:
+			e.Op = opt
 		case Kind:
 			e.Kind = opt
 		case MetaData:
@@ -164,14 +428,32 @@ func E(err error, args ...interface{}) error {
 
 			e.Meta = meta
 		case *Error:
+			if opt == nil {
+				continue
+			}
+
 			// Make a copy
 			copy := *opt
+			if MultipleCauses {
+				e.causes = append(e.causes, &copy)
+				continue
+			}
 			e.cause = &copy
+		case error:
+			if MultipleCauses && !IsNil(opt) {
+				e.causes = append(e.causes, opt)
+			}
+		case fmt.Stringer:
+			stringer = opt
 			//default:
 			//	return Errorf("unknown type %T, value %v in error call", arg, arg)
 		}
 	}
 
+	if !hasMsg && stringer != nil {
+		e.s = stringer.String()
+	}
+
 	// Fill missing fileds in case previous error is a Error type
 	if err, ok := e.cause.(*Error); ok {
 		if e.Kind == Unknown {
@@ -183,14 +465,127 @@ func E(err error, args ...interface{}) error {
 		}
 	}
 
+	// Custom error types can advertise its kind too
+	if k, ok := e.cause.(Kinder); ok && e.Kind == Unknown {
+		e.Kind = k.ErrorKind()
+	}
+
+	if DedupMessages && e.s != "" && e.s == topMsg(e.cause) {
+		e.s = ""
+	}
+
+	if MaxChainDepth > 0 {
+		e.collapse(MaxChainDepth)
+	}
+
 	return e
 }
 
+// DedupMessages makes E skip the msg if it's identical to the top msg
+// of the cause, avoiding outputs like "foo: foo: bar". It is disabled
+// by default
+var DedupMessages bool
+
+// topMsg returns the msg of the outermost layer of err
+func topMsg(err error) string {
+	if e, ok := err.(*Error); ok {
+		return e.Msg()
+	}
+
+	str := err.Error()
+	if pos := strings.Index(str, ":"); pos != -1 {
+		str = str[:pos]
+	}
+
+	return str
+}
+
+// MaxChainDepth is the max number of *Error layers E builds in a chain,
+// when it is exceeded only the newest layers are kept and the rest are
+// summarized along with the innermost cause. 0 means unlimited
+var MaxChainDepth int
+
+// collapse keeps the newest max layers of the chain, the layers are
+// copied so errors sharing the chain are left untouched
+func (e *Error) collapse(max int) {
+	depth := 0
+	for err := error(e); ; depth++ {
+		layer, ok := err.(*Error)
+		if !ok || layer == nil {
+			break
+		}
+		err = layer.cause
+	}
+
+	if depth <= max {
+		return
+	}
+
+	last := e
+	for i := 1; i < max; i++ {
+		c := *last.cause.(*Error)
+		last.cause = &c
+		last = &c
+	}
+
+	// find the innermost cause, previous collapses are accumulated
+	n := depth - max
+	root := last.cause
+	for {
+		layer, ok := root.(*Error)
+		if !ok || layer == nil {
+			break
+		}
+		root = layer.cause
+	}
+
+	if c, ok := root.(*collapsed); ok {
+		n += c.layers
+		root = c.root
+	}
+
+	last.cause = &collapsed{layers: n, root: root}
+}
+
+// collapsed summarizes the layers removed by MaxChainDepth
+type collapsed struct {
+	layers int
+	root   error
+}
+
+func (c *collapsed) Error() string {
+	return strconv.Itoa(c.layers) + " layers collapsed: " + c.root.Error()
+}
+
+// Cause returns the innermost cause of the collapsed layers
+func (c *collapsed) Cause() error {
+	return c.root
+}
+
+// Unwrap returns the innermost cause of the collapsed layers
+func (c *collapsed) Unwrap() error {
+	return c.root
+}
+
 // Msg returns the last known error msg, this is used to
 // show a friendly msg to end user ,instead the full trace
-// and to avoid leak of internal info
+// and to avoid leak of internal info. Layers without msg, e.g. only
+// adding an op, are skipped, so a msg set by WithUserMessage is kept
 func (e *Error) Msg() string {
-	str := e.Error()
+	layer := e
+	for layer.s == "" {
+		inner, ok := layer.cause.(*Error)
+		if !ok || inner == nil {
+			break
+		}
+		layer = inner
+	}
+
+	if layer.userMsg {
+		return layer.s
+	}
+
+	str := e.text(false)
 	pos := strings.Index(str, ":")
 	if pos == -1 {
 		pos = len(str)
@@ -199,99 +594,1536 @@ func (e *Error) Msg() string {
 	return str[:pos]
 }
 
+// EmptyMsgKind makes Error render layers without msg as their
+// kind between brackets, e.g. "[internal error]: no part of group",
+// instead of skipping them. It is disabled by default
+var EmptyMsgKind bool
+
 // Error format the output, joining all previous errors
+// along with its ops, e.g. "users.Get: user not found: io error"
 func (e *Error) Error() string {
+	return e.text(true)
+}
+
+// text joins the op, msg and causes of the error, the ops of the
+// whole chain are skipped if ops is false
+func (e *Error) text(ops bool) string {
 	str := e.s
-	// skip ':' if e.s it's empty
-	if str != "" {
+	if str == "" && EmptyMsgKind {
+		str = "[" + e.Kind.String() + "]"
+	}
+
+	if ops && e.Op != "" {
+		if str != "" {
+			str = ": " + str
+		}
+		str = string(e.Op) + str
+	}
+
+	cause := e.causeText(ops)
+	// skip ':' if e.s or the cause it's empty
+	if str != "" && cause != "" {
 		str += ": "
 	}
-	return str + e.cause.Error()
+	return str + cause
 }
 
-// Cause returns the underlaying error
-func (e *Error) Cause() error {
-	return e.cause
+// causeText joins the msg of all the causes, empty ones are skipped
+func (e *Error) causeText(ops bool) string {
+	msgOf := func(err error) string {
+		if c, ok := err.(*Error); ok && c != nil {
+			return c.text(ops)
+		}
+		return err.Error()
+	}
+
+	var str string
+	if e.cause != nil {
+		str = msgOf(e.cause)
+	}
+
+	for _, cause := range e.causes {
+		msg := msgOf(cause)
+		if msg == "" {
+			continue
+		}
+
+		if str != "" {
+			str += "; "
+		}
+		str += msg
+	}
+
+	return str
 }
 
-// StatusCode returns an http.StatusCode based on error kind
-func (e *Error) StatusCode() int {
-	return e.Kind.StatusCode()
+// Is reports whether e matches target, it is used by errors.Is.
+// An *Error without cause, except Zero, acts as a kind sentinel, it
+// matches any *Error of the same kind:
+//
+//	var ErrNotExist = &errors.Error{Kind: errors.NotExist}
+//	errors.Is(err, ErrNotExist)
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t == nil || t == Zero || t.cause != nil {
+		return false
+	}
+
+	return e.Kind == t.Kind
 }
 
-// MarshalJSON determines how the error will be serialized
-// if data is available, it will be serialized as is
-// otherwise the error will be serialized as dict with key "error"
-func (e *Error) MarshalJSON() ([]byte, error) {
-	return json.Marshal(struct {
-		Detail MetaData `json:"detail,omitempty"`
-		Type   string   `json:"type"`
-		Error  string   `json:"error"`
-		Code   Kind     `json:"code"`
-	}{
-		Error:  e.Msg(),
-		Detail: e.Meta,
-		Type:   e.Kind.String(),
-		Code:   e.Kind,
-	})
+// Ops returns the ops of the chain, from the outermost to the
+// innermost, layers without op are skipped. It reconstructs the
+// logical call path of the error
+func (e *Error) Ops() []string {
+	var ops []string
+	for err := error(e); !IsNil(err); err = next(err) {
+		if layer, ok := err.(*Error); ok && layer.Op != "" {
+			ops = append(ops, string(layer.Op))
+		}
+	}
+
+	return ops
 }
 
-// Recreate the errors.New functionality of the standard Go errors package
-// so we can create simple text errors when needed.
+// ErrorWithCode works like Error but prefixes the output with the
+// numeric code of the kind, e.g. "[code=3] io error: network unreachable",
+// useful for log pipelines parsing text
+func (e *Error) ErrorWithCode() string {
+	return "[code=" + strconv.Itoa(int(e.Kind)) + "] " + e.Error()
+}
 
-// New returns an error that formats as the given text. It is intended to
-// be used as the error-typed argument to the E function.
-func New(text string) error {
-	return &errorString{text}
+// Unwrap returns the causes of the error, it allows errors.Is and
+// errors.As from the standard library to inspect the whole chain. A nil
+// cause, e.g. for sentinels, is left out
+func (e *Error) Unwrap() []error {
+	if e.cause == nil {
+		return append([]error(nil), e.causes...)
+	}
+
+	return append([]error{e.cause}, e.causes...)
 }
 
-// errorString is a trivial implementation of error.
-type errorString struct {
-	s string
+// DebugString works like CauseChain, additionally the metadata of each
+// layer is rendered with its keys sorted, so the output is stable, e.g.
+//
+//	[NotExist] user not found {user=bob} -> [IO] io error {host=db retries=3} -> network unreachable
+func (e *Error) DebugString() string {
+	var b strings.Builder
+	var err error = e
+	for !IsNil(err) {
+		if b.Len() > 0 {
+			b.WriteString(" -> ")
+		}
+
+		layer, ok := err.(*Error)
+		if !ok {
+			b.WriteString(err.Error())
+			break
+		}
+
+		b.WriteString("[" + layer.Kind.name() + "]")
+		if layer.s != "" {
+			b.WriteString(" " + layer.s)
+		}
+
+		if len(layer.Meta) > 0 {
+			b.WriteString(" " + layer.Meta.render())
+		}
+		err = layer.cause
+	}
+
+	return b.String()
 }
 
-func (e *errorString) Error() string {
-	return e.s
+// AtLevel renders the error for a logger with the given verbosity, when
+// verbose the whole chain is rendered by DebugString, otherwise only the
+// kind and Msg of the top layer, e.g. "[NotExist] user not found"
+func (e *Error) AtLevel(verbose bool) string {
+	if verbose {
+		return e.DebugString()
+	}
+
+	s := "[" + e.Kind.name() + "]"
+	if msg := e.Msg(); msg != "" {
+		s += " " + msg
+	}
+
+	return s
 }
 
-// Errorf is equivalent to errors.Errorf, but allows clients to import only this
-// package for all error handling.
-func Errorf(format string, args ...interface{}) error {
-	return &errorString{fmt.Sprintf(format, args...)}
+// keys returns the keys of the metadata sorted
+func (m MetaData) keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
 }
 
-// Cause returns the underlying cause of the error, if possible.
-// An error value has a cause if it implements the following
-// interface:
+// render formats the metadata as {key=value ...} sorted by key, unlike
+// a map its output is deterministic
+func (m MetaData) render() string {
+	keys := m.keys()
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, m[k])
+	}
+
+	return "{" + strings.Join(pairs, " ") + "}"
+}
+
+// CauseChain renders every layer of the chain along with its kind,
+// it is more informative than Error for debugging, e.g.
 //
-//     type causer interface {
-//            Cause() error
-//     }
+//	[Permission] no part of group -> [Decrypt] invalid key -> network unreachable
+func (e *Error) CauseChain() string {
+	var b strings.Builder
+	var err error = e
+	for !IsNil(err) {
+		if b.Len() > 0 {
+			b.WriteString(" -> ")
+		}
+
+		layer, ok := err.(*Error)
+		if !ok {
+			b.WriteString(err.Error())
+			break
+		}
+
+		b.WriteString("[" + layer.Kind.name() + "]")
+		if layer.s != "" {
+			b.WriteString(" " + layer.s)
+		}
+		err = layer.cause
+	}
+
+	return b.String()
+}
+
+// Format implements fmt.Formatter, the supported verbs are:
 //
-// If the error does not implement Cause, the original error will
-// be returned. If the error is nil, nil will be returned without further
-// investigation.
-func Cause(err error) error {
-	type causer interface {
-		Cause() error
+//	%s, %v  the output of Error
+//	%+v     the output of CauseChain, every layer along with its kind
+//	%q      the output of Error, quoted
+//	%d      the http.StatusCode of the error
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, e.CauseChain())
+			return
+		}
+		io.WriteString(s, e.Error())
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	case 'd':
+		fmt.Fprintf(s, "%d", e.StatusCode())
+	default:
+		fmt.Fprintf(s, "%%!%c(*errors.Error=%s)", verb, e.Error())
 	}
+}
 
-	for err != nil {
-		cause, ok := err.(causer)
+// Cause returns the underlaying error
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// ErrorKind returns the kind of the error, it implements Kinder
+func (e *Error) ErrorKind() Kind {
+	return e.Kind
+}
+
+// StatusCode returns an http.StatusCode based on error kind
+func (e *Error) StatusCode() int {
+	return e.Kind.StatusCode()
+}
+
+// EffectiveStatusCode works like StatusCode, but the kind is given by
+// KindOf, so with the default KindResolution an Unknown top layer
+// reports the kind of a deeper layer
+func (e *Error) EffectiveStatusCode() int {
+	return KindOf(e).StatusCode()
+}
+
+// IsServerFault reports whether the error is our fault rather than
+// the client's, that is its kind maps to a 5xx http.StatusCode,
+// e.g. Internal, IO, Unknown or Transient
+func (e *Error) IsServerFault() bool {
+	return e.StatusCode() >= http.StatusInternalServerError
+}
+
+// IsClientFault reports whether the error is the client's fault,
+// that is its kind maps to a 4xx http.StatusCode. For every kind it is
+// the complement of IsServerFault
+func (e *Error) IsClientFault() bool {
+	status := e.StatusCode()
+	return status >= http.StatusBadRequest && status < http.StatusInternalServerError
+}
+
+// MessageKey is the key used by MarshalJSON to serialize the msg of
+// the error, it can be changed to match the API contract, e.g. "message"
+var MessageKey = "error"
+
+// ChainMode defines how MarshalJSON builds the "chain" field
+type ChainMode uint8
+
+// Modes of the "chain" field.
+const (
+	// ChainNone omits the "chain" field.
+	ChainNone ChainMode = iota
+	// ChainMessages lists the msg of every layer on its own,
+	// e.g. ["no part of group", "invalid key", "network unreachable"]
+	ChainMessages
+	// ChainCumulative lists the output of Error for every layer,
+	// e.g. ["invalid key: network unreachable", "network unreachable"]
+	ChainCumulative
+)
+
+// JSONChain determines whether MarshalJSON includes every layer of the
+// chain under the "chain" field and how. It exposes internal details,
+// so it is ChainNone by default
+var JSONChain = ChainNone
+
+// chain lists the layers of the error according to mode, the
+// innermost non *Error cause is always listed as is
+func (e *Error) chain(mode ChainMode) []string {
+	var out []string
+	var err error = e
+	for !IsNil(err) {
+		layer, ok := err.(*Error)
 		if !ok {
+			out = append(out, err.Error())
 			break
 		}
-		err = cause.Cause()
+
+		// layers without msg add nothing to the chain
+		switch {
+		case layer.s == "":
+		case mode == ChainMessages:
+			out = append(out, layer.s)
+		case mode == ChainCumulative:
+			out = append(out, layer.Error())
+		}
+		err = layer.cause
 	}
-	return err
+
+	return out
 }
 
-// IsKind is a convenience function that determines if the
-// kind of the provided error value matches that of the
-// provided kind.
+// PublicError returns the text that is safe to show to end users, it
+// is the output of Msg or the description of the kind if it's empty.
+// Like Msg, it never includes ops nor the cause chain
+func (e *Error) PublicError() string {
+	msg := e.Msg()
+	if msg == "" {
+		msg = e.Kind.String()
+	}
+
+	return msg
+}
+
+// MarshalJSON determines how the error will be serialized
+// if data is available, it will be serialized as is
+// otherwise the error will be serialized as dict with key "error"
+// or the one defined by MessageKey, its value is PublicError.
+// Metadata values of type json.RawMessage are embedded as is, so
+// pre-serialized fragments aren't encoded twice
+func (e *Error) MarshalJSON() ([]byte, error) {
+	if marshalHook != nil {
+		if c := marshalHook(e.clone(nil)); c != nil {
+			e = c
+		}
+	}
+
+	msg := e.PublicError()
+
+	fields := make([]field, 0, 4)
+	if len(e.Meta) > 0 || EmitEmptyDetail && e.Meta != nil {
+		detail, err := marshalMeta(e.Meta)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, field{"detail", detail})
+	}
+
+	fields = append(fields,
+		field{"type", e.Kind.String()},
+		field{MessageKey, msg},
+		field{"code", e.Kind},
+	)
+
+	if e.Op != "" {
+		fields = append(fields, field{"op", e.Op})
+	}
+
+	if JSONChain != ChainNone {
+		fields = append(fields, field{"chain", e.chain(JSONChain)})
+	}
+
+	for i := range fields {
+		fields[i].key = KeyCase.apply(fields[i].key)
+	}
+
+	return marshalObject(fields)
+}
+
+// KeyCasing is the casing of the keys serialized by MarshalJSON
+type KeyCasing uint8
+
+// Casings for KeyCase
+const (
+	// AsIs renders the keys as they are given
+	AsIs KeyCasing = iota
+	// SnakeCase renders the keys as "error_message"
+	SnakeCase
+	// CamelCase renders the keys as "errorMessage"
+	CamelCase
+)
+
+// KeyCase is the casing of the keys of the fields serialized by
+// MarshalJSON, including MessageKey. The keys of the metadata are chosen
+// by the caller, so they are serialized as is. It defaults to SnakeCase,
+// the built-in keys are already snake case, so only a custom MessageKey,
+// e.g. "errorMessage", is renamed. Use AsIs to leave every key untouched
+var KeyCase = SnakeCase
+
+// apply renders key, given in snake or camel case, in the casing c.
+// Acronyms are kept together, e.g. "userID" is "user_id" in snake case
+func (c KeyCasing) apply(key string) string {
+	if c == AsIs {
+		return key
+	}
+
+	var b strings.Builder
+	runes := []rune(key)
+	boundary := false
+	for i, r := range runes {
+		if r == '_' || r == '-' {
+			boundary = b.Len() > 0
+			continue
+		}
+
+		if i > 0 && unicode.IsUpper(r) {
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(runes[i-1]) || nextLower {
+				boundary = b.Len() > 0
+			}
+		}
+
+		switch {
+		case c == SnakeCase && boundary:
+			b.WriteByte('_')
+			r = unicode.ToLower(r)
+		case c == SnakeCase:
+			r = unicode.ToLower(r)
+		case boundary:
+			r = unicode.ToUpper(r)
+		}
+
+		boundary = false
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// SerializedSize returns the size in bytes of the output of MarshalJSON,
+// e.g. to avoid emitting oversized log lines
+func (e *Error) SerializedSize() (int, error) {
+	b, err := e.MarshalJSON()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// EmitEmptyDetail makes MarshalJSON emit "detail":{} for an explicitly
+// empty metadata, to distinguish it from a nil one, which is always
+// omitted. It is disabled by default, both of them are omitted
+var EmitEmptyDetail bool
+
+// marshalHook is the hook set by SetMarshalHook
+var marshalHook func(*Error) *Error
+
+// SetMarshalHook sets a function applied by MarshalJSON to the error
+// right before serializing it, it allows to apply the output policy,
+// e.g. redaction, in a single place. fn receives a deep copy of the
+// error, so it can modify it freely without affecting the original, a
+// nil result serializes the copy as is. A nil fn removes the hook.
+// It is intended to be called during initialization
+func SetMarshalHook(fn func(*Error) *Error) {
+	marshalHook = fn
+}
+
+// metaMarshaler is the hook set by SetMetaMarshaler
+var metaMarshaler func(MetaData) (json.RawMessage, error)
+
+// SetMetaMarshaler sets the function used by MarshalJSON to serialize
+// the "detail" field, it allows to control centrally how values like
+// time.Time are serialized. A nil fn restores the default marshaling.
+// It is intended to be called during initialization
+func SetMetaMarshaler(fn func(MetaData) (json.RawMessage, error)) {
+	metaMarshaler = fn
+}
+
+// MaxMetaBytes is the max size of the json representation of a single
+// metadata value serialized by MarshalJSON, bigger values are replaced
+// by "[truncated]". 0 means unlimited
+var MaxMetaBytes int
+
+// truncatedMeta replaces the values of the metadata exceeding
+// MaxMetaBytes, the metadata is copied only if needed
+func truncatedMeta(m MetaData) MetaData {
+	if MaxMetaBytes <= 0 {
+		return m
+	}
+
+	var out MetaData
+	for k, v := range m {
+		b, err := json.Marshal(v)
+		if err != nil || len(b) <= MaxMetaBytes {
+			continue
+		}
+
+		if out == nil {
+			out = make(MetaData, len(m))
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+		out[k] = "[truncated]"
+	}
+
+	if out == nil {
+		return m
+	}
+
+	return out
+}
+
+// marshalMeta serializes the metadata using the hook if it's set
+func marshalMeta(m MetaData) (json.RawMessage, error) {
+	m = truncatedMeta(m)
+	if metaMarshaler != nil {
+		return metaMarshaler(m)
+	}
+
+	return json.Marshal(m)
+}
+
+// MarshalList serializes errs inside an envelope under the key "errors",
+// e.g. for bulk endpoints. Errors that aren't an *Error are serialized
+// as if they were wrapped by E, nil errors are skipped
+func MarshalList(errs []error) ([]byte, error) {
+	list := make([]json.Marshaler, 0, len(errs))
+	for _, err := range errs {
+		if IsNil(err) {
+			continue
+		}
+
+		e, ok := err.(*Error)
+		if !ok {
+			e = &Error{cause: err, Kind: KindOf(err)}
+		}
+
+		list = append(list, e)
+	}
+
+	return marshalObject([]field{{"errors", list}})
+}
+
+// field is a key/value pair of a json object
+type field struct {
+	key   string
+	value interface{}
+}
+
+// marshalObject serializes fields as a json object, unlike a map
+// the order of the keys is preserved
+func marshalObject(fields []field) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// gobError is the wire representation of Error used by gob
+type gobError struct {
+	Op    Op       `json:"op,omitempty"`
+	Kind  Kind     `json:"kind"`
+	Msg   string   `json:"msg"`
+	Cause string   `json:"cause"`
+	Meta  MetaData `json:"meta,omitempty"`
+	// Whether Msg was set by WithUserMessage
+	UserMsg bool `json:"user_msg,omitempty"`
+}
+
+// GobEncode implements gob.GobEncoder, so errors can be transported by
+// net/rpc. The kind, msg and metadata are kept while the cause chain is
+// flattened into a single msg. Metadata is encoded as json, so values
+// must be json serializable
+func (e *Error) GobEncode() ([]byte, error) {
+	return json.Marshal(gobError{
+		Op:      e.Op,
+		Kind:    e.Kind,
+		Msg:     e.s,
+		Cause:   e.causeText(true),
+		Meta:    e.Meta,
+		UserMsg: e.userMsg,
+	})
+}
+
+// GobDecode implements gob.GobDecoder, see GobEncode
+func (e *Error) GobDecode(data []byte) error {
+	var g gobError
+	if err := json.Unmarshal(data, &g); err != nil {
+		return err
+	}
+
+	*e = Error{
+		Op:      g.Op,
+		Kind:    g.Kind,
+		s:       g.Msg,
+		cause:   New(g.Cause),
+		Meta:    g.Meta,
+		userMsg: g.UserMsg,
+	}
+
+	return nil
+}
+
+// ParseJSON rebuilds an error from the json produced by MarshalJSON, e.g.
+// the body of a response from another service using this package. The
+// kind is taken from "code", the msg from MessageKey, the metadata from
+// "detail" and the op from "op". The msg is already the public one, so
+// it's reported as is by Msg, as set by WithUserMessage. The cause chain
+// isn't part of the json, an empty leaf cause takes its place, so the
+// result isn't a kind sentinel. An Unmarshal error is returned if data
+// can't be parsed
+func ParseJSON(data []byte) (*Error, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, E(err, "can't parse error", Unmarshal)
+	}
+
+	var msg string
+	e := &Error{}
+	targets := []struct {
+		key  string
+		dest interface{}
+	}{
+		{KeyCase.apply("code"), &e.Kind},
+		{KeyCase.apply(MessageKey), &msg},
+		{KeyCase.apply("detail"), &e.Meta},
+		{KeyCase.apply("op"), &e.Op},
+	}
+
+	for _, t := range targets {
+		raw, ok := fields[t.key]
+		if !ok {
+			continue
+		}
+
+		if err := json.Unmarshal(raw, t.dest); err != nil {
+			return nil, E(err, "can't parse error field "+t.key, Unmarshal)
+		}
+	}
+
+	if msg == "" {
+		msg = e.Kind.String()
+	}
+	e.s = msg
+	e.userMsg = true
+	e.cause = New("")
+
+	return e, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see ParseJSON
+func (e *Error) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseJSON(data)
+	if err != nil {
+		return err
+	}
+
+	*e = *parsed
+	return nil
+}
+
+// Recreate the errors.New functionality of the standard Go errors package
+// so we can create simple text errors when needed.
+
+// New returns an error that formats as the given text. It is intended to
+// be used as the error-typed argument to the E function.
+func New(text string) error {
+	return &errorString{text}
+}
+
+// errorString is a trivial implementation of error.
+type errorString struct {
+	s string
+}
+
+func (e *errorString) Error() string {
+	return e.s
+}
+
+// Errorf is equivalent to errors.Errorf, but allows clients to import only this
+// package for all error handling.
+func Errorf(format string, args ...interface{}) error {
+	return &errorString{fmt.Sprintf(format, args...)}
+}
+
+// NotFound wraps err as a NotExist error, it is a shortcut of
+// E(err, msg, NotExist)
+func NotFound(err error, msg string) error {
+	return E(err, msg, NotExist)
+}
+
+// Conflict wraps err as a Duplicated error, it is a shortcut of
+// E(err, msg, Duplicated)
+func Conflict(err error, msg string) error {
+	return E(err, msg, Duplicated)
+}
+
+// Unauthorized wraps err as a Permission error, it is a shortcut of
+// E(err, msg, Permission)
+func Unauthorized(err error, msg string) error {
+	return E(err, msg, Permission)
+}
+
+// Invalidf builds an Invalid error from a format specifier
+func Invalidf(format string, args ...interface{}) error {
+	return E(Errorf(format, args...), Invalid)
+}
+
+// Internalf builds an Internal error from a format specifier
+func Internalf(format string, args ...interface{}) error {
+	return E(Errorf(format, args...), Internal)
+}
+
+// Transientf builds a Transient error from a format specifier
+func Transientf(format string, args ...interface{}) error {
+	return E(Errorf(format, args...), Transient)
+}
+
+// Kinded builds a standalone error of the given kind, there is no
+// underlying error so the description of the kind is used as the cause,
+// e.g. Kinded(NotExist, "user not found") formats as
+// "user not found: item does not exist"
+func Kinded(kind Kind, msg string) error {
+	return E(New(kind.String()), msg, kind)
+}
+
+// NotImplemented builds an Unimplemented error, it is intended to
+// be used by stubs to report the operation is not available yet
+func NotImplemented(msg string) error {
+	return Kinded(Unimplemented, msg)
+}
+
+// WrapIf wraps err with kind and msg only if cond is true, otherwise err
+// is returned unchanged, e.g.
+//
+//	return WrapIf(isRetryable(err), err, Transient, "fetching user")
+//
+// Like E, nil is returned if err is nil
+func WrapIf(cond bool, err error, kind Kind, msg string) error {
+	if !cond {
+		return err
+	}
+
+	return E(err, kind, msg)
+}
+
+// CloseWith closes closer and reports its error, wrapped with kind and
+// msg, through err. It is intended to be deferred with the address of a
+// named return:
+//
+//	func read(name string) (err error) {
+//		f, err := os.Open(name)
+//		...
+//		defer CloseWith(&err, f, IO, "closing file")
+//
+// If err already holds an error it stays the primary one, with its kind
+// and status unchanged, the close error is attached as an additional
+// cause, so Unwrap yields both of them
+func CloseWith(err *error, closer io.Closer, kind Kind, msg string) {
+	cerr := closer.Close()
+	if IsNil(cerr) {
+		return
+	}
+
+	cerr = E(cerr, kind, msg)
+	if IsNil(*err) {
+		*err = cerr
+		return
+	}
+
+	e, ok := (*err).(*Error)
+	if !ok {
+		*err = &Error{Kind: KindOf(*err), cause: *err, causes: []error{cerr}}
+		return
+	}
+
+	c := *e
+	c.causes = append(append([]error(nil), e.causes...), cerr)
+	*err = &c
+}
+
+// MissingValue is stored by EKV as the value of a key without value
+const MissingValue = "(MISSING)"
+
+// EKV works like E, the metadata is given as alternating key/value
+// pairs instead of a MetaData map, e.g.
+//
+//	EKV(err, NotExist, "user not found", "user_id", 42, "table", "users")
+//
+// Keys that aren't strings are formatted with fmt.Sprint, a trailing key
+// without value gets MissingValue
+func EKV(cause error, kind Kind, msg string, kv ...interface{}) error {
+	if len(kv) == 0 {
+		return E(cause, kind, msg)
+	}
+
+	meta := make(MetaData, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+
+		if i+1 < len(kv) {
+			meta[key] = kv[i+1]
+		} else {
+			meta[key] = MissingValue
+		}
+	}
+
+	return E(cause, kind, msg, meta)
+}
+
+// FromRecover converts the value returned by recover into an Internal
+// error, nil is returned if there was no panic
+//
+//	defer func() {
+//		err = errors.FromRecover(recover())
+//	}()
+func FromRecover(r interface{}) error {
+	if r == nil {
+		return nil
+	}
+
+	err, ok := r.(error)
+	if !ok {
+		err = Errorf("%v", r)
+	}
+
+	return E(err, "panic recovered", Internal)
+}
+
+// Go runs fn in a new goroutine and delivers its result, nil included,
+// on the returned channel, which is buffered so the goroutine never
+// blocks. A panic in fn is delivered as an error built by FromRecover
+func Go(fn func() error) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		var err error
+		defer func() {
+			if rerr := FromRecover(recover()); rerr != nil {
+				err = rerr
+			}
+			ch <- err
+		}()
+
+		err = fn()
+	}()
+
+	return ch
+}
+
+// Cause returns the underlying cause of the error, if possible.
+// An error value has a cause if it implements the following
+// interface:
+//
+//     type causer interface {
+//            Cause() error
+//     }
+//
+// If the error does not implement Cause, the original error will
+// be returned. If the error is nil, nil will be returned without further
+// investigation.
+func Cause(err error) error {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		cause, ok := err.(causer)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return err
+}
+
+// IsKind is a convenience function that determines if the
+// kind of the provided error value matches that of the
+// provided kind. The chain is walked the same way as KindOf,
+// an error without any *Error or Kinder in its chain never matches.
 func IsKind(err error, kind Kind) bool {
-	if e, ok := err.(*Error); ok {
-		return e.Kind == kind
+	k, ok := kindOf(err)
+	return ok && k == kind
+}
+
+// IsTransient reports whether err is worth retrying, that is its kind,
+// as reported by KindOf, is Transient or Timeout
+func IsTransient(err error) bool {
+	switch KindOf(err) {
+	case Transient, Timeout:
+		return true
+	}
+
+	return false
+}
+
+// KindOf returns the kind of the chain of err as resolved by
+// KindResolution, by default the first kind other than Unknown found
+// walking the chain. Both *Error and any error implementing Kinder
+// are consulted. Unknown is returned if no kind is found.
+func KindOf(err error) Kind {
+	k, _ := kindOf(err)
+	return k
+}
+
+// IsAny reports whether errors.Is holds for err and any of targets
+func IsAny(err error, targets ...error) bool {
+	for _, target := range targets {
+		if stderrors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchMeta reports whether any *Error in the chain of err has the
+// metadata key set to value, e.g. a specific subcode
+func MatchMeta(err error, key string, value interface{}) bool {
+	for ; !IsNil(err); err = next(err) {
+		e, ok := err.(*Error)
+		if !ok {
+			continue
+		}
+
+		if v, ok := e.Meta[key]; ok && reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ToStd rebuilds the chain of err using fmt.Errorf("%s: %w"), so the
+// result is a pure standard library chain, for code that introspects
+// those chains specifically. Kinds and metadata are lost, ops are kept
+// as part of the msg. Only the first cause of each layer is kept
+func ToStd(err error) error {
+	e, ok := err.(*Error)
+	if !ok || e == nil {
+		return err
+	}
+
+	cause := ToStd(e.cause)
+	msg := e.s
+	if e.Op != "" {
+		if msg != "" {
+			msg = ": " + msg
+		}
+		msg = string(e.Op) + msg
+	}
+
+	switch {
+	case cause == nil:
+		return stderrors.New(msg)
+	case msg == "":
+		return cause
+	}
+
+	return fmt.Errorf("%s: %w", msg, cause)
+}
+
+// Externalize applies the policy for errors leaving a package: server
+// faults and Decrypt errors are reclassified as a generic Internal error
+// with a safe msg, so internal details are hidden from its Msg and
+// MarshalJSON. The original error is kept as its Cause, so it is still
+// available for logging. Any other error is returned unchanged
+func Externalize(err error) error {
+	if IsNil(err) {
+		return nil
+	}
+
+	kind := KindOf(err)
+	if kind != Decrypt && ToStatus(err) < http.StatusInternalServerError {
+		return err
+	}
+
+	return &Error{
+		Kind:  Internal,
+		s:     Internal.String(),
+		cause: err,
+	}
+}
+
+// Sanitize drops everything but the kind of err, for errors crossing a
+// trust boundary. The result is a single layer *Error with the kind of
+// err as reported by KindOf, no metadata and no internal cause. Client
+// faults keep the first msg set on an *Error layer of err, the text of
+// other errors is never used, server faults only get the description
+// of the kind
+func Sanitize(err error) error {
+	if IsNil(err) {
+		return nil
+	}
+
+	kind := KindOf(err)
+	e := &Error{
+		Kind:  kind,
+		cause: New(kind.String()),
+	}
+
+	if kind.StatusCode() >= http.StatusInternalServerError {
+		return e
+	}
+
+	for layer := err; !IsNil(layer); layer = next(layer) {
+		if le, ok := layer.(*Error); ok && le.s != "" {
+			if le.s != kind.String() {
+				e.s = le.s
+				e.userMsg = le.userMsg
+			}
+			break
+		}
+	}
+
+	return e
+}
+
+// SameKind reports whether a and b have the same kind, as reported by
+// KindOf. Errors without a kind are considered Unknown
+func SameKind(a, b error) bool {
+	return KindOf(a) == KindOf(b)
+}
+
+// MostSevere returns the error whose kind, as reported by KindOf, has
+// the highest SeverityRank, the first one wins on a tie. It returns nil
+// if every error is nil
+func MostSevere(errs ...error) error {
+	var worst error
+	rank := -1
+	for _, err := range errs {
+		if IsNil(err) {
+			continue
+		}
+
+		if r := KindOf(err).SeverityRank(); r > rank {
+			worst, rank = err, r
+		}
+	}
+
+	return worst
+}
+
+// Combine returns the kind that best describes two failures together:
+// a server fault takes precedence over a client fault, and any kind
+// takes precedence over Unknown. On a tie a is returned
+func Combine(a, b Kind) Kind {
+	if kindRank(b) > kindRank(a) {
+		return b
+	}
+
+	return a
+}
+
+func kindRank(k Kind) int {
+	switch {
+	case k == Unknown:
+		return 0
+	case k.StatusCode() < http.StatusInternalServerError:
+		return 1
+	}
+
+	return 2
+}
+
+// MergeChains combines the chains of two independent failures into one
+// error, Unwrap yields both of them and the kind is given by Combine.
+// Error shows both chains separated by "; "
+func MergeChains(a, b error) error {
+	switch {
+	case IsNil(a):
+		return b
+	case IsNil(b):
+		return a
+	}
+
+	return &Error{
+		Kind:   Combine(KindOf(a), KindOf(b)),
+		cause:  a,
+		causes: []error{b},
+	}
+}
+
+// Filter returns the *Error layers in the chain of err for which
+// pred returns true, from the outermost to the innermost
+func Filter(err error, pred func(*Error) bool) []*Error {
+	var layers []*Error
+	for ; !IsNil(err); err = next(err) {
+		if e, ok := err.(*Error); ok && pred(e) {
+			layers = append(layers, e)
+		}
+	}
+
+	return layers
+}
+
+// Resolution is a strategy to pick the kind of a chain that has more
+// than one kind, see KindResolution
+type Resolution uint8
+
+// Strategies for KindResolution
+const (
+	// Outermost uses the kind of the outermost layer advertising one,
+	// even if it is Unknown
+	Outermost Resolution = iota
+	// Innermost uses the innermost kind other than Unknown, that is
+	// the kind closest to the root cause
+	Innermost
+	// FirstKnown uses the outermost kind other than Unknown
+	FirstKnown
+)
+
+// KindResolution is the strategy used by KindOf, IsKind and
+// EffectiveStatusCode to pick the kind of a chain. It defaults to
+// FirstKnown, not Outermost, which is how KindOf has always resolved the
+// kind: with Outermost, every E(err, "msg") without a kind would report
+// Unknown and hide the kind of err. Set it to Outermost to opt in
+var KindResolution = FirstKnown
+
+// kindOf resolves the kind of the chain using KindResolution, it also
+// reports whether any layer was able to advertise a kind at all
+func kindOf(err error) (Kind, bool) {
+	return resolveKind(err, KindResolution)
+}
+
+func resolveKind(err error, strategy Resolution) (Kind, bool) {
+	kind, found := Unknown, false
+	for ; !IsNil(err); err = next(err) {
+		k, ok := err.(Kinder)
+		if !ok {
+			continue
+		}
+
+		found = true
+		current := k.ErrorKind()
+		switch {
+		case strategy == Outermost:
+			return current, true
+		case current == Unknown:
+		case strategy == FirstKnown:
+			return current, true
+		default:
+			kind = current
+		}
+	}
+
+	return kind, found
+}
+
+// next returns the error wrapped by err, either via Cause or the
+// standard Unwrap, nil is returned if err doesn't wrap anything
+func next(err error) error {
+	switch e := err.(type) {
+	case interface{ Cause() error }:
+		return e.Cause()
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
 	}
+
+	return nil
+}
+
+// IsNil reports whether err is logically nil. Besides the untyped nil
+// it also detects a typed nil, like a nil *Error, stored in an error
+// interface, which compares as non nil:
+//
+//	var e *Error
+//	var err error = e
+//	err != nil  // true
+//	IsNil(err)  // true
+func IsNil(err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return true
+	case *Error:
+		return e == nil
+	case *errorString:
+		return e == nil
+	}
+
+	v := reflect.ValueOf(err)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+
 	return false
 }
+
+// WithMeta returns a copy of the error with m merged into its
+// metadata, the keys of m take precedence. The original error and its
+// metadata are left untouched
+func (e *Error) WithMeta(m MetaData) *Error {
+	c := *e
+	c.Meta = make(MetaData, len(e.Meta)+len(m))
+	for k, v := range e.Meta {
+		c.Meta[k] = v
+	}
+
+	for k, v := range m {
+		c.Meta[k] = v
+	}
+
+	return &c
+}
+
+// WithMetaReplaced returns a copy of the error with its metadata
+// replaced by a copy of m, unlike WithMeta the previous metadata is
+// dropped. The original error and m are left untouched
+func (e *Error) WithMetaReplaced(m MetaData) *Error {
+	c := *e
+	c.Meta = nil
+	if m != nil {
+		c.Meta = make(MetaData, len(m))
+		for k, v := range m {
+			c.Meta[k] = v
+		}
+	}
+
+	return &c
+}
+
+// WithField is an alias of WithMeta for a single key, it matches the
+// API of popular logging libraries
+func (e *Error) WithField(key string, value interface{}) *Error {
+	return e.WithMeta(MetaData{key: value})
+}
+
+// WithFields is an alias of WithMeta, it matches the API of popular
+// logging libraries
+func (e *Error) WithFields(m MetaData) *Error {
+	return e.WithMeta(m)
+}
+
+// MarkLogged returns a copy of the error marked as logged, so the
+// loggers further up skip it, see WasLogged. Like WithMeta, the original
+// error is left untouched
+func (e *Error) MarkLogged() *Error {
+	c := *e
+	c.logged = true
+	return &c
+}
+
+// WasLogged reports whether any *Error in the chain was marked via
+// MarkLogged, so wrapping a logged error keeps it marked
+func (e *Error) WasLogged() bool {
+	for err := error(e); !IsNil(err); err = next(err) {
+		if layer, ok := err.(*Error); ok && layer.logged {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithDuration returns a copy of the error with how long the failing
+// operation took stored in its metadata under the key "duration_ms",
+// as milliseconds
+func (e *Error) WithDuration(d time.Duration) *Error {
+	return e.WithMeta(MetaData{"duration_ms": d.Milliseconds()})
+}
+
+// Duration returns the duration stored by WithDuration in the chain,
+// numbers decoded from json, e.g. via GobDecode, are accepted as well
+func (e *Error) Duration() (time.Duration, bool) {
+	v, ok := e.LookupMeta("duration_ms")
+	if !ok {
+		return 0, false
+	}
+
+	var ms int64
+	switch n := v.(type) {
+	case int64:
+		ms = n
+	case int:
+		ms = int64(n)
+	case float64:
+		ms = int64(n)
+	default:
+		return 0, false
+	}
+
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// BindMeta unmarshals the metadata of the whole chain into dest, like
+// json.Unmarshal does. The metadata of the outer layers takes
+// precedence. An Unmarshal error is returned if it isn't compatible
+//
+//	var ctx RequestContext
+//	err.BindMeta(&ctx)
+func (e *Error) BindMeta(dest interface{}) error {
+	b, err := json.Marshal(e.mergedMeta())
+	if err != nil {
+		return E(err, "can't marshal metadata", Unmarshal)
+	}
+
+	if err := json.Unmarshal(b, dest); err != nil {
+		return E(err, "can't bind metadata", Unmarshal)
+	}
+
+	return nil
+}
+
+// mergedMeta merges the metadata of every *Error in the chain, the
+// outer layers take precedence
+func (e *Error) mergedMeta() MetaData {
+	var layers []MetaData
+	for err := error(e); !IsNil(err); err = next(err) {
+		if layer, ok := err.(*Error); ok && layer.Meta != nil {
+			layers = append(layers, layer.Meta)
+		}
+	}
+
+	meta := MetaData{}
+	for i := len(layers) - 1; i >= 0; i-- {
+		for k, v := range layers[i] {
+			meta[k] = v
+		}
+	}
+
+	return meta
+}
+
+// HasMeta reports whether any *Error in the chain has key in its Meta
+func (e *Error) HasMeta(key string) bool {
+	_, ok := e.LookupMeta(key)
+	return ok
+}
+
+// LookupMeta returns the value of key from the outermost *Error in the
+// chain that has it in its Meta
+func (e *Error) LookupMeta(key string) (interface{}, bool) {
+	for err := error(e); !IsNil(err); err = next(err) {
+		layer, ok := err.(*Error)
+		if !ok {
+			continue
+		}
+
+		if v, ok := layer.Meta[key]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+// WithUserMessage returns an error with msg as the user facing msg,
+// as reported by Msg and MarshalJSON, while the original error is kept
+// as its cause so Error still shows the full chain for internal logging.
+// Unlike regular msgs, it is reported as is, even if it contains a ':'
+func (e *Error) WithUserMessage(msg string) *Error {
+	return &Error{
+		Kind:    e.Kind,
+		cause:   e,
+		s:       msg,
+		Meta:    e.Meta,
+		userMsg: true,
+	}
+}
+
+// ReclassifyAll returns a deep copy of the chain where the kind of every
+// *Error layer is set to k, the original error is left untouched.
+// It is useful to sanitize errors that will be surfaced uniformly
+func (e *Error) ReclassifyAll(k Kind) *Error {
+	return e.MapKinds(func(Kind) Kind {
+		return k
+	})
+}
+
+// MapKinds returns a deep copy of the chain where the kind of every
+// *Error layer is transformed by fn, the original error is left
+// untouched, e.g. to upgrade every Unknown to Internal
+func (e *Error) MapKinds(fn func(Kind) Kind) *Error {
+	if e == nil {
+		return nil
+	}
+
+	return e.clone(func(layer *Error) {
+		layer.Kind = fn(layer.Kind)
+	})
+}
+
+// CollapseKinds returns a deep copy of the chain where consecutive
+// *Error layers with the same kind are merged into one, to reduce the
+// noise when displaying it. The msgs of the merged layers are joined
+// with ": " and their metadata is merged, the outer layers take
+// precedence. Layers with a msg set by WithUserMessage are never merged,
+// so their msg is reported as is. The original error is left untouched
+func (e *Error) CollapseKinds() *Error {
+	if e == nil {
+		return nil
+	}
+
+	c := e.clone(nil)
+	for layer := c; ; {
+		inner, ok := layer.cause.(*Error)
+		if !ok || inner == nil {
+			break
+		}
+
+		if inner.Kind != layer.Kind || layer.userMsg || inner.userMsg {
+			layer = inner
+			continue
+		}
+
+		switch {
+		case layer.s == "":
+			layer.s = inner.s
+		case inner.s != "":
+			layer.s += ": " + inner.s
+		}
+
+		if layer.Op == "" {
+			layer.Op = inner.Op
+		}
+
+		for k, v := range inner.Meta {
+			if layer.Meta == nil {
+				layer.Meta = MetaData{}
+			}
+			if _, ok := layer.Meta[k]; !ok {
+				layer.Meta[k] = v
+			}
+		}
+
+		for k, v := range inner.header {
+			if layer.header == nil {
+				layer.header = http.Header{}
+			}
+			if _, ok := layer.header[k]; !ok {
+				layer.header[k] = v
+			}
+		}
+
+		if inner.stack != nil {
+			layer.stack = inner.stack
+		}
+
+		layer.causes = append(layer.causes, inner.causes...)
+		layer.cause = inner.cause
+	}
+
+	return c
+}
+
+// Reparent returns a deep copy of chain where the innermost cause is
+// replaced by root, it allows to reuse the layers of a chain under a
+// new root. Like E, nil is returned if root is nil
+func Reparent(root error, chain *Error) error {
+	if IsNil(root) {
+		return nil
+	}
+
+	if chain == nil {
+		return root
+	}
+
+	c := chain.clone(nil)
+	last := c
+	for {
+		layer, ok := last.cause.(*Error)
+		if !ok || layer == nil {
+			break
+		}
+		last = layer
+	}
+	last.cause = root
+
+	return c
+}
+
+// clone makes a deep copy of every *Error layer in the chain, fn is
+// called on each copy so it can be modified safely
+func (e *Error) clone(fn func(*Error)) *Error {
+	c := *e
+	if c.Meta != nil {
+		meta := make(MetaData, len(c.Meta))
+		for k, v := range c.Meta {
+			meta[k] = v
+		}
+
+		c.Meta = meta
+	}
+
+	if cause, ok := c.cause.(*Error); ok && cause != nil {
+		c.cause = cause.clone(fn)
+	}
+
+	c.header = c.header.Clone()
+
+	if c.causes != nil {
+		causes := make([]error, len(c.causes))
+		for i, cause := range c.causes {
+			if err, ok := cause.(*Error); ok && err != nil {
+				cause = err.clone(fn)
+			}
+			causes[i] = cause
+		}
+
+		c.causes = causes
+	}
+
+	if fn != nil {
+		fn(&c)
+	}
+
+	return &c
+}
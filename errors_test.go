@@ -1,10 +1,18 @@
 package errors
 
 import (
+	"bytes"
+	"encoding/gob"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"net/http"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestError_E(t *testing.T) {
@@ -161,6 +169,190 @@ func TestError_MarshalJSON(t *testing.T) {
 
 }
 
+func TestError_MarshalJSON_EmptyMsg(t *testing.T) {
+	b, err := json.Marshal(E(New(""), NotExist))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expect := `{"type":"item does not exist","error":"item does not exist","code":5}`
+	if string(b) != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, string(b))
+	}
+}
+
+func TestError_MarshalJSON_Chain(t *testing.T) {
+	defer func(mode ChainMode) { JSONChain = mode }(JSONChain)
+
+	errDecrypt := E(E(New("network unreachable"), IO), "invalid key", Decrypt)
+	err := E(errDecrypt, "no part of group", Permission)
+
+	tc := []struct {
+		name   string
+		mode   ChainMode
+		expect string
+	}{
+		{
+			name:   "none",
+			mode:   ChainNone,
+			expect: `{"type":"permission denied","error":"no part of group","code":2}`,
+		},
+		{
+			name:   "messages",
+			mode:   ChainMessages,
+			expect: `{"type":"permission denied","error":"no part of group","code":2,"chain":["no part of group","invalid key","network unreachable"]}`,
+		},
+		{
+			name:   "cumulative",
+			mode:   ChainCumulative,
+			expect: `{"type":"permission denied","error":"no part of group","code":2,"chain":["no part of group: invalid key: network unreachable","invalid key: network unreachable","network unreachable"]}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			JSONChain = tt.mode
+			b, jerr := json.Marshal(err)
+			if jerr != nil {
+				t.Error(jerr)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
+
+func TestSetMetaMarshaler(t *testing.T) {
+	defer SetMetaMarshaler(nil)
+
+	created := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := E(New("foo"), "network latency", IO, MetaData{"created": created})
+
+	tc := []struct {
+		name      string
+		marshaler func(MetaData) (json.RawMessage, error)
+		expect    string
+	}{
+		{
+			name:   "default",
+			expect: `{"detail":{"created":"2021-01-02T03:04:05Z"},"type":"I/O error","error":"network latency","code":3}`,
+		},
+		{
+			name: "custom",
+			marshaler: func(m MetaData) (json.RawMessage, error) {
+				out := make(map[string]interface{}, len(m))
+				for k, v := range m {
+					if t, ok := v.(time.Time); ok {
+						v = t.Unix()
+					}
+					out[k] = v
+				}
+
+				return json.Marshal(out)
+			},
+			expect: `{"detail":{"created":1609556645},"type":"I/O error","error":"network latency","code":3}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			SetMetaMarshaler(tt.marshaler)
+			b, jerr := json.Marshal(err)
+			if jerr != nil {
+				t.Error(jerr)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
+
+func TestError_MarshalJSON_MaxMetaBytes(t *testing.T) {
+	defer func(max int) { MaxMetaBytes = max }(MaxMetaBytes)
+
+	meta := MetaData{"blob": strings.Repeat("x", 100), "user": "bob"}
+	err := E(New("foo"), "network latency", IO, meta)
+
+	tc := []struct {
+		name   string
+		max    int
+		expect string
+	}{
+		{
+			name:   "unlimited",
+			max:    0,
+			expect: `{"detail":{"blob":"` + strings.Repeat("x", 100) + `","user":"bob"},"type":"I/O error","error":"network latency","code":3}`,
+		},
+		{
+			name:   "limited",
+			max:    16,
+			expect: `{"detail":{"blob":"[truncated]","user":"bob"},"type":"I/O error","error":"network latency","code":3}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			MaxMetaBytes = tt.max
+			b, jerr := json.Marshal(err)
+			if jerr != nil {
+				t.Error(jerr)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+
+	if len(meta["blob"].(string)) != 100 {
+		t.Error("the metadata should not be modified")
+	}
+}
+
+func TestError_MarshalJSON_MessageKey(t *testing.T) {
+	defer func(key string) { MessageKey = key }(MessageKey)
+
+	tc := []struct {
+		name   string
+		key    string
+		expect string
+	}{
+		{
+			name:   "default key",
+			key:    "error",
+			expect: `{"type":"I/O error","error":"network latency","code":3}`,
+		},
+		{
+			name:   "custom key",
+			key:    "message",
+			expect: `{"type":"I/O error","message":"network latency","code":3}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			MessageKey = tt.key
+			b, err := json.Marshal(E(New("foo"), "network latency", IO))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
+
 func TestError_Error(t *testing.T) {
 	errIO := E(New("network unreachable"), "io error", IO)
 	errUnmarshal := E(errIO, "can't unmarshal bar", Unmarshal)
@@ -187,6 +379,16 @@ func TestError_Error(t *testing.T) {
 			err:    megaError,
 			expect: "no part of group: invalid key: can't unmarshal bar: io error: network unreachable",
 		},
+		{
+			name:   "empty cause msg",
+			err:    E(New(""), "bar"),
+			expect: "bar",
+		},
+		{
+			name:   "empty cause msg in the middle",
+			err:    E(E(New(""), "bar"), "baz"),
+			expect: "baz: bar",
+		},
 	}
 
 	for _, tt := range tc {
@@ -247,3 +449,2897 @@ func TestIsKind(t *testing.T) {
 		})
 	}
 }
+
+func TestIsNil(t *testing.T) {
+	var typedNil *Error
+
+	tc := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{
+			name:   "nil error",
+			err:    nil,
+			expect: true,
+		},
+		{
+			name:   "typed nil errors.Error",
+			err:    typedNil,
+			expect: true,
+		},
+		{
+			name:   "typed nil custom error",
+			err:    (*customErr)(nil),
+			expect: true,
+		},
+		{
+			name:   "std error",
+			err:    fmt.Errorf("some error"),
+			expect: false,
+		},
+		{
+			name:   "errors.Error",
+			err:    E(New("foo"), "bar"),
+			expect: false,
+		},
+		{
+			name:   "non pointer custom error",
+			err:    kinder{kind: IO},
+			expect: false,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsNil(tt.err)
+			if tt.expect != got {
+				t.Errorf("\ntest: %s\nexpected: %t\n     got: %t", tt.name, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestError_ReclassifyAll(t *testing.T) {
+	errIO := E(New("network unreachable"), "io error", IO)
+	errUnmarshal := E(errIO, "can't unmarshal bar", Unmarshal)
+	errDecrypt := E(errUnmarshal, "invalid key", Decrypt, MetaData{"foo": "bar"})
+
+	orig := errDecrypt.(*Error)
+	got := orig.ReclassifyAll(Internal)
+
+	for layer := error(got); layer != nil; {
+		e, ok := layer.(*Error)
+		if !ok {
+			break
+		}
+
+		if e.Kind != Internal {
+			t.Errorf("\nexpected: %s\n     got: %s", Internal, e.Kind)
+		}
+		layer = e.Cause()
+	}
+
+	if got.Error() != orig.Error() {
+		t.Errorf("\nexpected: %s\n     got: %s", orig.Error(), got.Error())
+	}
+
+	expect := []Kind{Decrypt, Unmarshal, IO}
+	for i, layer := 0, error(orig); i < len(expect); i++ {
+		e := layer.(*Error)
+		if e.Kind != expect[i] {
+			t.Errorf("\noriginal modified\nexpected: %s\n     got: %s", expect[i], e.Kind)
+		}
+		layer = e.Cause()
+	}
+
+	got.Meta["foo"] = "baz"
+	if orig.Meta["foo"] != "bar" {
+		t.Errorf("\noriginal metadata modified\nexpected: %s\n     got: %s", "bar", orig.Meta["foo"])
+	}
+}
+
+// kinder is a custom error type advertising its own kind
+type kinder struct {
+	kind Kind
+}
+
+func (k kinder) Error() string {
+	return "custom error"
+}
+
+func (k kinder) ErrorKind() Kind {
+	return k.kind
+}
+
+func TestKindOf(t *testing.T) {
+	tc := []struct {
+		name   string
+		err    error
+		expect Kind
+	}{
+		{
+			name:   "no error",
+			err:    nil,
+			expect: Unknown,
+		},
+		{
+			name:   "std error",
+			err:    fmt.Errorf("some error"),
+			expect: Unknown,
+		},
+		{
+			name:   "errors.Error",
+			err:    E(New("foo"), Duplicated),
+			expect: Duplicated,
+		},
+		{
+			name:   "custom kinder",
+			err:    kinder{kind: NotExist},
+			expect: NotExist,
+		},
+		{
+			name:   "custom kinder wrapped by errors.Error",
+			err:    E(kinder{kind: NotExist}, "getting user"),
+			expect: NotExist,
+		},
+		{
+			name:   "errors.Error wrapped by std error",
+			err:    fmt.Errorf("wrapped: %w", E(New("foo"), Permission)),
+			expect: Permission,
+		},
+		{
+			name:   "unknown layer on top of a known kind",
+			err:    &Error{cause: kinder{kind: Timeout}},
+			expect: Timeout,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := KindOf(tt.err)
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestIsKind_Kinder(t *testing.T) {
+	tc := []struct {
+		name   string
+		err    error
+		kind   Kind
+		expect bool
+	}{
+		{
+			name:   "custom kinder",
+			err:    kinder{kind: NotExist},
+			kind:   NotExist,
+			expect: true,
+		},
+		{
+			name:   "custom kinder wrong kind",
+			err:    kinder{kind: NotExist},
+			kind:   Invalid,
+			expect: false,
+		},
+		{
+			name:   "custom kinder deep in the chain",
+			err:    &Error{cause: fmt.Errorf("wrapped: %w", kinder{kind: IO})},
+			kind:   IO,
+			expect: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsKind(tt.err, tt.kind)
+			if tt.expect != got {
+				t.Errorf("\ntest: %s\nexpected: %t\n     got: %t", tt.name, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestError_CauseChain(t *testing.T) {
+	errIO := E(New("network unreachable"), "io error", IO)
+	errUnmarshal := E(errIO, "can't unmarshal bar", Unmarshal)
+	errDecrypt := E(errUnmarshal, "invalid key", Decrypt)
+	megaError := E(errDecrypt, "no part of group", Permission)
+
+	tc := []struct {
+		name   string
+		err    error
+		expect string
+	}{
+		{
+			name:   "no msg",
+			err:    E(New("foo")),
+			expect: "[Unknown] -> foo",
+		},
+		{
+			name:   "with msg",
+			err:    E(New("foo"), "bar", NotExist),
+			expect: "[NotExist] bar -> foo",
+		},
+		{
+			name:   "multiple underlaying errors",
+			err:    megaError,
+			expect: "[Permission] no part of group -> [Decrypt] invalid key -> [Unmarshal] can't unmarshal bar -> [IO] io error -> network unreachable",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.err.(*Error).CauseChain()
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	errDummy := New("foo")
+
+	tc := []struct {
+		name         string
+		err          error
+		expectKind   Kind
+		expectStatus int
+		expectMsg    string
+	}{
+		{
+			name:         "NotFound",
+			err:          NotFound(errDummy, "user not found"),
+			expectKind:   NotExist,
+			expectStatus: http.StatusNotFound,
+			expectMsg:    "user not found",
+		},
+		{
+			name:         "Conflict",
+			err:          Conflict(errDummy, "user already exists"),
+			expectKind:   Duplicated,
+			expectStatus: http.StatusConflict,
+			expectMsg:    "user already exists",
+		},
+		{
+			name:         "Unauthorized",
+			err:          Unauthorized(errDummy, "missing token"),
+			expectKind:   Permission,
+			expectStatus: http.StatusUnauthorized,
+			expectMsg:    "missing token",
+		},
+		{
+			name:         "Invalidf",
+			err:          Invalidf("invalid age %d", -1),
+			expectKind:   Invalid,
+			expectStatus: http.StatusBadRequest,
+			expectMsg:    "invalid age -1",
+		},
+		{
+			name:         "Internalf",
+			err:          Internalf("inconsistent state %s", "foo"),
+			expectKind:   Internal,
+			expectStatus: http.StatusInternalServerError,
+			expectMsg:    "inconsistent state foo",
+		},
+		{
+			name:         "Transientf",
+			err:          Transientf("db %s unavailable", "users"),
+			expectKind:   Transient,
+			expectStatus: http.StatusServiceUnavailable,
+			expectMsg:    "db users unavailable",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err, ok := tt.err.(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if tt.expectKind != err.Kind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, err.Kind)
+			}
+
+			if status := err.StatusCode(); tt.expectStatus != status {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectStatus, status)
+			}
+
+			if msg := err.Msg(); tt.expectMsg != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+		})
+	}
+
+	if err := NotFound(nil, "user not found"); err != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", err)
+	}
+}
+
+func TestError_Error_EmptyMsgKind(t *testing.T) {
+	defer func(v bool) { EmptyMsgKind = v }(EmptyMsgKind)
+
+	errDecrypt := E(E(New("network unreachable"), IO), "invalid key", Decrypt)
+	err := E(E(errDecrypt, "no part of group", Permission), Internal)
+
+	tc := []struct {
+		name   string
+		flag   bool
+		expect string
+	}{
+		{
+			name:   "disabled",
+			flag:   false,
+			expect: "no part of group: invalid key: network unreachable",
+		},
+		{
+			name:   "enabled",
+			flag:   true,
+			expect: "[internal error]: no part of group: invalid key: [I/O error]: network unreachable",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			EmptyMsgKind = tt.flag
+			msg := err.Error()
+			if tt.expect != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, msg)
+			}
+		})
+	}
+}
+
+func TestE_MultipleCauses(t *testing.T) {
+	defer func(v bool) { MultipleCauses = v }(MultipleCauses)
+
+	errDB := New("db unreachable")
+	errCache := New("cache unreachable")
+
+	tc := []struct {
+		name          string
+		flag          bool
+		expectMsg     string
+		expectCauses  int
+		expectIsDB    bool
+		expectIsCache bool
+	}{
+		{
+			name:          "disabled",
+			flag:          false,
+			expectMsg:     "syncing: reading cache: cache unreachable",
+			expectCauses:  1,
+			expectIsDB:    false,
+			expectIsCache: true,
+		},
+		{
+			name:          "enabled",
+			flag:          true,
+			expectMsg:     "syncing: db unreachable; reading cache: cache unreachable",
+			expectCauses:  2,
+			expectIsDB:    true,
+			expectIsCache: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			MultipleCauses = tt.flag
+			err := E(errDB, "syncing", E(errCache, "reading cache", IO))
+			if msg := err.Error(); tt.expectMsg != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+
+			if causes := len(err.(*Error).Unwrap()); tt.expectCauses != causes {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectCauses, causes)
+			}
+
+			if is := stderrors.Is(err, errDB); tt.expectIsDB != is {
+				t.Errorf("\nerrors.Is db\nexpected: %t\n     got: %t", tt.expectIsDB, is)
+			}
+
+			if is := stderrors.Is(err, errCache); tt.expectIsCache != is {
+				t.Errorf("\nerrors.Is cache\nexpected: %t\n     got: %t", tt.expectIsCache, is)
+			}
+		})
+	}
+}
+
+func TestError_Unwrap(t *testing.T) {
+	tc := []struct {
+		name   string
+		err    *Error
+		expect int
+	}{
+		{name: "single cause", err: E(New("foo"), "bar").(*Error), expect: 1},
+		{name: "sentinel", err: &Error{Kind: NotExist}, expect: 0},
+		{name: "zero", err: Zero, expect: 0},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			causes := tt.err.Unwrap()
+			if len(causes) != tt.expect {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expect, len(causes))
+			}
+
+			for _, cause := range causes {
+				if cause == nil {
+					t.Error("nil cause returned")
+				}
+			}
+		})
+	}
+}
+
+func TestError_IsServerFault(t *testing.T) {
+	tc := []struct {
+		kind   Kind
+		expect bool
+	}{
+		{kind: Unknown, expect: true},
+		{kind: Invalid, expect: false},
+		{kind: Permission, expect: false},
+		{kind: IO, expect: true},
+		{kind: Duplicated, expect: false},
+		{kind: NotExist, expect: false},
+		{kind: Private, expect: false},
+		{kind: Internal, expect: true},
+		{kind: Decrypt, expect: false},
+		{kind: Unmarshal, expect: false},
+		{kind: Transient, expect: true},
+		{kind: Unsupported, expect: false},
+		{kind: NotAcceptable, expect: false},
+		{kind: Timeout, expect: false},
+		{kind: Unimplemented, expect: true},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			got := E(New("foo"), tt.kind).(*Error).IsServerFault()
+			if tt.expect != got {
+				t.Errorf("\nkind: %s\nexpected: %t\n     got: %t", tt.kind, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestError_IsClientFault(t *testing.T) {
+	tc := []struct {
+		kind   Kind
+		expect bool
+	}{
+		{kind: Unknown, expect: false},
+		{kind: Invalid, expect: true},
+		{kind: Permission, expect: true},
+		{kind: IO, expect: false},
+		{kind: Duplicated, expect: true},
+		{kind: NotExist, expect: true},
+		{kind: Private, expect: true},
+		{kind: Internal, expect: false},
+		{kind: Decrypt, expect: true},
+		{kind: Unmarshal, expect: true},
+		{kind: Transient, expect: false},
+		{kind: Unsupported, expect: true},
+		{kind: NotAcceptable, expect: true},
+		{kind: Timeout, expect: true},
+		{kind: Unimplemented, expect: false},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			err := E(New("foo"), tt.kind).(*Error)
+			got := err.IsClientFault()
+			if tt.expect != got {
+				t.Errorf("\nkind: %s\nexpected: %t\n     got: %t", tt.kind, tt.expect, got)
+			}
+
+			if got == err.IsServerFault() {
+				t.Errorf("\nkind: %s\nIsClientFault should be the complement of IsServerFault", tt.kind)
+			}
+		})
+	}
+}
+
+func TestKind_Code(t *testing.T) {
+	defer func(prefix string) { CodePrefix = prefix }(CodePrefix)
+
+	tc := []struct {
+		name   string
+		prefix string
+		kind   Kind
+		expect string
+	}{
+		{
+			name:   "single word",
+			kind:   Internal,
+			expect: "INTERNAL",
+		},
+		{
+			name:   "acronym",
+			kind:   IO,
+			expect: "IO",
+		},
+		{
+			name:   "multiple words",
+			kind:   NotExist,
+			expect: "NOT_EXIST",
+		},
+		{
+			name:   "with prefix",
+			prefix: "USERS",
+			kind:   NotExist,
+			expect: "USERS_NOT_EXIST",
+		},
+		{
+			name:   "with prefix acronym",
+			prefix: "USERS",
+			kind:   IO,
+			expect: "USERS_IO",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			CodePrefix = tt.prefix
+			got := tt.kind.Code()
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestError_Format(t *testing.T) {
+	err := E(E(New("network unreachable"), "io error", IO), "user not found", NotExist)
+
+	tc := []struct {
+		format string
+		expect string
+	}{
+		{
+			format: "%s",
+			expect: "user not found: io error: network unreachable",
+		},
+		{
+			format: "%v",
+			expect: "user not found: io error: network unreachable",
+		},
+		{
+			format: "%+v",
+			expect: "[NotExist] user not found -> [IO] io error -> network unreachable",
+		},
+		{
+			format: "%q",
+			expect: `"user not found: io error: network unreachable"`,
+		},
+		{
+			format: "%d",
+			expect: "404",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.format, func(t *testing.T) {
+			got := fmt.Sprintf(tt.format, err)
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	errIO := E(New("network unreachable"), "io error", IO)
+	errUnmarshal := E(errIO, "can't unmarshal bar", Unmarshal)
+	errInternal := E(fmt.Errorf("wrapped: %w", errUnmarshal), "inconsistent state", Internal)
+	megaError := E(errInternal, "no part of group", Permission)
+
+	tc := []struct {
+		name   string
+		pred   func(*Error) bool
+		expect []string
+	}{
+		{
+			name:   "server faults",
+			pred:   (*Error).IsServerFault,
+			expect: []string{"inconsistent state", "io error"},
+		},
+		{
+			name:   "client faults",
+			pred:   (*Error).IsClientFault,
+			expect: []string{"no part of group", "can't unmarshal bar"},
+		},
+		{
+			name:   "no match",
+			pred:   func(e *Error) bool { return e.Kind == Timeout },
+			expect: nil,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, layer := range Filter(megaError, tt.pred) {
+				got = append(got, layer.Msg())
+			}
+
+			if !reflect.DeepEqual(tt.expect, got) {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestNotImplemented(t *testing.T) {
+	err, ok := NotImplemented("export users").(*Error)
+	if !ok {
+		t.Error("invalid error, should be of type errors.Error")
+		return
+	}
+
+	if err.Kind != Unimplemented {
+		t.Errorf("\nexpected: %s\n     got: %s", Unimplemented, err.Kind)
+	}
+
+	if expect := "not implemented"; err.Kind.String() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, err.Kind.String())
+	}
+
+	if status := err.StatusCode(); status != http.StatusNotImplemented {
+		t.Errorf("\nexpected: %d\n     got: %d", http.StatusNotImplemented, status)
+	}
+
+	if expect := "export users: not implemented"; err.Error() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, err.Error())
+	}
+}
+
+func TestFromRecover(t *testing.T) {
+	errDummy := New("foo")
+
+	tc := []struct {
+		name   string
+		rec    interface{}
+		expect string
+	}{
+		{
+			name:   "error",
+			rec:    errDummy,
+			expect: "panic recovered: foo",
+		},
+		{
+			name:   "string",
+			rec:    "bar",
+			expect: "panic recovered: bar",
+		},
+		{
+			name:   "any value",
+			rec:    42,
+			expect: "panic recovered: 42",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromRecover(tt.rec)
+			if !IsKind(err, Internal) {
+				t.Errorf("\nexpected: %s\n     got: %s", Internal, KindOf(err))
+			}
+
+			if msg := err.Error(); tt.expect != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, msg)
+			}
+		})
+	}
+
+	if err := FromRecover(nil); err != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", err)
+	}
+}
+
+func TestSameKind(t *testing.T) {
+	tc := []struct {
+		name   string
+		a      error
+		b      error
+		expect bool
+	}{
+		{
+			name:   "same kind",
+			a:      E(New("foo"), NotExist),
+			b:      E(E(New("bar"), NotExist), "wrapped"),
+			expect: true,
+		},
+		{
+			name:   "different kind",
+			a:      E(New("foo"), NotExist),
+			b:      E(New("foo"), Invalid),
+			expect: false,
+		},
+		{
+			name:   "plain errors",
+			a:      New("foo"),
+			b:      fmt.Errorf("bar"),
+			expect: true,
+		},
+		{
+			name:   "plain error and unknown kind",
+			a:      New("foo"),
+			b:      E(New("bar")),
+			expect: true,
+		},
+		{
+			name:   "plain error and known kind",
+			a:      New("foo"),
+			b:      E(New("bar"), IO),
+			expect: false,
+		},
+		{
+			name:   "custom kinder",
+			a:      kinder{kind: Timeout},
+			b:      E(New("bar"), Timeout),
+			expect: true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SameKind(tt.a, tt.b)
+			if tt.expect != got {
+				t.Errorf("\ntest: %s\nexpected: %t\n     got: %t", tt.name, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestError_Gob(t *testing.T) {
+	errIO := E(New("network unreachable"), "io error", IO)
+	err := E(errIO, "user not found", NotExist, MetaData{"user": "bob"}).(*Error)
+
+	var buf bytes.Buffer
+	if gerr := gob.NewEncoder(&buf).Encode(err); gerr != nil {
+		t.Error(gerr)
+		return
+	}
+
+	got := &Error{}
+	if gerr := gob.NewDecoder(&buf).Decode(got); gerr != nil {
+		t.Error(gerr)
+		return
+	}
+
+	if got.Kind != err.Kind {
+		t.Errorf("\nexpected: %s\n     got: %s", err.Kind, got.Kind)
+	}
+
+	if got.Error() != err.Error() {
+		t.Errorf("\nexpected: %s\n     got: %s", err.Error(), got.Error())
+	}
+
+	if got.Msg() != err.Msg() {
+		t.Errorf("\nexpected: %s\n     got: %s", err.Msg(), got.Msg())
+	}
+
+	if !reflect.DeepEqual(got.Meta, err.Meta) {
+		t.Errorf("\nexpected: %v\n     got: %v", err.Meta, got.Meta)
+	}
+}
+
+func TestError_WithMeta(t *testing.T) {
+	orig := E(New("foo"), "bar", IO, MetaData{"user": "bob"}).(*Error)
+
+	tc := []struct {
+		name   string
+		err    *Error
+		expect MetaData
+	}{
+		{
+			name:   "WithMeta",
+			err:    orig.WithMeta(MetaData{"request_id": "abc"}),
+			expect: MetaData{"user": "bob", "request_id": "abc"},
+		},
+		{
+			name:   "WithField",
+			err:    orig.WithField("request_id", "abc"),
+			expect: MetaData{"user": "bob", "request_id": "abc"},
+		},
+		{
+			name:   "WithFields",
+			err:    orig.WithFields(MetaData{"request_id": "abc", "user": "alice"}),
+			expect: MetaData{"user": "alice", "request_id": "abc"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.expect, tt.err.Meta) {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, tt.err.Meta)
+			}
+
+			if tt.err.Error() != orig.Error() || tt.err.Kind != orig.Kind {
+				t.Errorf("\nexpected: %+v\n     got: %+v", orig, tt.err)
+			}
+		})
+	}
+
+	if expect := (MetaData{"user": "bob"}); !reflect.DeepEqual(expect, orig.Meta) {
+		t.Errorf("\noriginal modified\nexpected: %v\n     got: %v", expect, orig.Meta)
+	}
+}
+
+func TestE_MaxChainDepth(t *testing.T) {
+	defer func(max int) { MaxChainDepth = max }(MaxChainDepth)
+
+	errRoot := New("network unreachable")
+
+	tc := []struct {
+		name   string
+		max    int
+		wraps  int
+		expect string
+	}{
+		{
+			name:   "unlimited",
+			max:    0,
+			wraps:  4,
+			expect: "retrying: retrying: retrying: retrying: network unreachable",
+		},
+		{
+			name:   "below the limit",
+			max:    3,
+			wraps:  2,
+			expect: "retrying: retrying: network unreachable",
+		},
+		{
+			name:   "above the limit",
+			max:    3,
+			wraps:  10,
+			expect: "retrying: retrying: retrying: 7 layers collapsed: network unreachable",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			MaxChainDepth = tt.max
+			err := errRoot
+			for i := 0; i < tt.wraps; i++ {
+				err = E(err, "retrying", Transient)
+			}
+
+			if msg := err.Error(); tt.expect != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, msg)
+			}
+
+			if !stderrors.Is(err, errRoot) {
+				t.Errorf("\nexpected %v to be reachable from %v", errRoot, err)
+			}
+
+			if Cause(err) != errRoot {
+				t.Errorf("\nexpected: %v\n     got: %v", errRoot, Cause(err))
+			}
+		})
+	}
+
+	MaxChainDepth = 2
+	shared := E(E(errRoot, "first"), "second")
+	E(shared, "third")
+	if expect := "second: first: network unreachable"; shared.Error() != expect {
+		t.Errorf("\nshared chain modified\nexpected: %s\n     got: %s", expect, shared.Error())
+	}
+}
+
+func TestError_BindMeta(t *testing.T) {
+	type requestContext struct {
+		RequestID string `json:"request_id"`
+		User      string `json:"user"`
+		Retries   int    `json:"retries"`
+	}
+
+	errIO := E(New("network unreachable"), IO, MetaData{"user": "bob", "retries": 3})
+	err := E(errIO, "getting user", MetaData{"request_id": "abc", "user": "alice"}).(*Error)
+
+	var got requestContext
+	if berr := err.BindMeta(&got); berr != nil {
+		t.Error(berr)
+		return
+	}
+
+	expect := requestContext{RequestID: "abc", User: "alice", Retries: 3}
+	if expect != got {
+		t.Errorf("\nexpected: %+v\n     got: %+v", expect, got)
+	}
+
+	var mismatch struct {
+		Retries string `json:"retries"`
+	}
+	berr := err.BindMeta(&mismatch)
+	if berr == nil {
+		t.Error("expected an error binding mismatching fields")
+		return
+	}
+
+	if !IsKind(berr, Unmarshal) {
+		t.Errorf("\nexpected: %s\n     got: %s", Unmarshal, KindOf(berr))
+	}
+}
+
+func TestError_DebugString(t *testing.T) {
+	meta := MetaData{"retries": 3, "host": "db", "attempt": 1, "zone": "us", "port": 5432}
+	errIO := E(New("network unreachable"), "io error", IO, meta)
+	err := E(errIO, "user not found", NotExist, MetaData{"user": "bob"}).(*Error)
+
+	expect := "[NotExist] user not found {user=bob} -> [IO] io error {attempt=1 host=db port=5432 retries=3 zone=us} -> network unreachable"
+	for i := 0; i < 50; i++ {
+		got := err.DebugString()
+		if expect != got {
+			t.Errorf("\nexpected: %s\n     got: %s", expect, got)
+			return
+		}
+	}
+}
+
+func TestError_ErrorWithCode(t *testing.T) {
+	err := E(New("network unreachable"), "io error", IO).(*Error)
+
+	expect := "[code=3] io error: network unreachable"
+	if got := err.ErrorWithCode(); expect != got {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, got)
+	}
+
+	if expect := "io error: network unreachable"; err.Error() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, err.Error())
+	}
+}
+
+// customErr is a pointer based custom error, its Error panics on nil
+type customErr struct {
+	msg string
+}
+
+func (c *customErr) Error() string {
+	return c.msg
+}
+
+func TestE_TypedNil(t *testing.T) {
+	var typedNil *customErr
+	var err error = typedNil
+
+	if got := E(err, "getting user", NotExist); got != nil {
+		t.Errorf("\nexpected: nil\n     got: %#v", got)
+	}
+
+	var typedNilError *Error
+	got := E(New("foo"), "getting user", typedNilError)
+	if expect := "getting user: foo"; got.Error() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, got.Error())
+	}
+}
+
+func TestIsAny(t *testing.T) {
+	errNotExist := &Error{Kind: NotExist}
+	errTimeout := &Error{Kind: Timeout}
+
+	tc := []struct {
+		name    string
+		err     error
+		targets []error
+		expect  bool
+	}{
+		{
+			name:    "kind sentinel",
+			err:     E(New("foo"), "getting user", NotExist),
+			targets: []error{io.EOF, errNotExist},
+			expect:  true,
+		},
+		{
+			name:    "stdlib sentinel",
+			err:     E(fmt.Errorf("reading body: %w", io.EOF), "getting user", Unmarshal),
+			targets: []error{io.EOF, errNotExist},
+			expect:  true,
+		},
+		{
+			name:    "kind sentinel deep in the chain",
+			err:     fmt.Errorf("wrapped: %w", E(E(New("foo"), Timeout), "bar")),
+			targets: []error{errNotExist, errTimeout},
+			expect:  true,
+		},
+		{
+			name:    "no match",
+			err:     E(New("foo"), "getting user", Invalid),
+			targets: []error{io.EOF, errNotExist},
+			expect:  false,
+		},
+		{
+			name:    "no targets",
+			err:     E(New("foo"), "getting user", Invalid),
+			targets: nil,
+			expect:  false,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsAny(tt.err, tt.targets...)
+			if tt.expect != got {
+				t.Errorf("\ntest: %s\nexpected: %t\n     got: %t", tt.name, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestMatchMeta(t *testing.T) {
+	errQuota := E(New("quota exceeded"), Permission, MetaData{"subcode": "quota"})
+	err := fmt.Errorf("wrapped: %w", E(errQuota, "uploading file", MetaData{"file": "a.txt"}))
+
+	tc := []struct {
+		name   string
+		key    string
+		value  interface{}
+		expect bool
+	}{
+		{
+			name:   "outer layer",
+			key:    "file",
+			value:  "a.txt",
+			expect: true,
+		},
+		{
+			name:   "inner layer",
+			key:    "subcode",
+			value:  "quota",
+			expect: true,
+		},
+		{
+			name:   "different value",
+			key:    "subcode",
+			value:  "rate",
+			expect: false,
+		},
+		{
+			name:   "missing key",
+			key:    "user",
+			value:  "bob",
+			expect: false,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MatchMeta(err, tt.key, tt.value)
+			if tt.expect != got {
+				t.Errorf("\ntest: %s\nexpected: %t\n     got: %t", tt.name, tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestError_WithUserMessage(t *testing.T) {
+	errIO := E(New("dial tcp 10.0.0.1:5432: connection refused"), "querying users", IO)
+	err := E(errIO, "getting user").(*Error).WithUserMessage("please try again later")
+
+	if expect := "please try again later"; err.Msg() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, err.Msg())
+	}
+
+	expect := "please try again later: getting user: querying users: dial tcp 10.0.0.1:5432: connection refused"
+	if err.Error() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, err.Error())
+	}
+
+	if err.Kind != IO {
+		t.Errorf("\nexpected: %s\n     got: %s", IO, err.Kind)
+	}
+
+	b, jerr := json.Marshal(err)
+	if jerr != nil {
+		t.Error(jerr)
+		return
+	}
+
+	if expect := `{"type":"I/O error","error":"please try again later","code":3}`; string(b) != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, string(b))
+	}
+
+	err = errIO.(*Error).WithUserMessage("Error: try again")
+	if expect := "Error: try again"; err.Msg() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, err.Msg())
+	}
+
+	if expect := "Error: try again"; err.PublicError() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, err.PublicError())
+	}
+}
+
+func TestError_WithUserMessage_kept(t *testing.T) {
+	u := E(New("no rows"), "query", Invalid).(*Error).WithUserMessage("Oops: try later")
+
+	gobbed := func(err *Error) error {
+		var buf bytes.Buffer
+		if gerr := gob.NewEncoder(&buf).Encode(err); gerr != nil {
+			return gerr
+		}
+
+		got := &Error{}
+		if gerr := gob.NewDecoder(&buf).Decode(got); gerr != nil {
+			return gerr
+		}
+		return got
+	}
+
+	parsed := func(err *Error) error {
+		b, jerr := json.Marshal(err)
+		if jerr != nil {
+			return jerr
+		}
+
+		got, perr := ParseJSON(b)
+		if perr != nil {
+			return perr
+		}
+		return got
+	}
+
+	tc := []struct {
+		name string
+		err  error
+	}{
+		{name: "wrapped with an op", err: E(u, Op("svc.Do"))},
+		{name: "ELoc", err: ELoc(u)},
+		{name: "Sanitize", err: Sanitize(u)},
+		{name: "gob round trip", err: gobbed(u)},
+		{name: "json round trip", err: parsed(u)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := tt.err.(*Error)
+			if !ok {
+				t.Errorf("\nexpected: *Error\n     got: %T %v", tt.err, tt.err)
+				return
+			}
+
+			if expect := "Oops: try later"; e.Msg() != expect {
+				t.Errorf("\nexpected: %s\n     got: %s", expect, e.Msg())
+			}
+		})
+	}
+}
+
+func TestKinded(t *testing.T) {
+	tc := []struct {
+		name         string
+		kind         Kind
+		msg          string
+		expectStatus int
+		expectMsg    string
+		expectError  string
+	}{
+		{
+			name:         "not exist",
+			kind:         NotExist,
+			msg:          "user not found",
+			expectStatus: http.StatusNotFound,
+			expectMsg:    "user not found",
+			expectError:  "user not found: item does not exist",
+		},
+		{
+			name:         "permission",
+			kind:         Permission,
+			msg:          "admins only",
+			expectStatus: http.StatusUnauthorized,
+			expectMsg:    "admins only",
+			expectError:  "admins only: permission denied",
+		},
+		{
+			name:         "no msg",
+			kind:         Duplicated,
+			expectStatus: http.StatusConflict,
+			expectMsg:    "item already exists",
+			expectError:  "item already exists",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err, ok := Kinded(tt.kind, tt.msg).(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if tt.kind != err.Kind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.kind, err.Kind)
+			}
+
+			if status := err.StatusCode(); tt.expectStatus != status {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectStatus, status)
+			}
+
+			if msg := err.Msg(); tt.expectMsg != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+
+			if msg := err.Error(); tt.expectError != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectError, msg)
+			}
+		})
+	}
+}
+
+func TestE_Op(t *testing.T) {
+	errIO := E(New("network unreachable"), Op("db.Query"), "io error", IO)
+	err := E(errIO, Op("users.Get"), "user not found", NotExist).(*Error)
+
+	if err.Op != "users.Get" {
+		t.Errorf("\nexpected: %s\n     got: %s", "users.Get", err.Op)
+	}
+
+	expect := "users.Get: user not found: db.Query: io error: network unreachable"
+	if got := err.Error(); expect != got {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, got)
+	}
+
+	b, jerr := json.Marshal(err)
+	if jerr != nil {
+		t.Error(jerr)
+		return
+	}
+
+	expect = `{"type":"item does not exist","error":"user not found","code":5,"op":"users.Get"}`
+	if string(b) != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, string(b))
+	}
+
+	onlyOp := E(New("foo"), Op("users.Get"))
+	if expect := "users.Get: foo"; onlyOp.Error() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, onlyOp.Error())
+	}
+}
+
+func TestError_Ops(t *testing.T) {
+	errDB := E(New("network unreachable"), Op("db.Query"), IO)
+	errRepo := E(errDB, "querying users")
+	errService := E(fmt.Errorf("wrapped: %w", errRepo), Op("users.Get"))
+	err := E(errService, Op("http.GetUser"), NotExist).(*Error)
+
+	expect := []string{"http.GetUser", "users.Get", "db.Query"}
+	if got := err.Ops(); !reflect.DeepEqual(expect, got) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, got)
+	}
+
+	if got := E(New("foo")).(*Error).Ops(); got != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", got)
+	}
+}
+
+func TestError_PublicError(t *testing.T) {
+	errDB := E(New("network unreachable"), Op("db.Query"), IO)
+
+	tc := []struct {
+		name         string
+		err          error
+		expectPublic string
+		expectError  string
+	}{
+		{
+			name:         "op and msg",
+			err:          E(errDB, Op("users.Get"), "user not found", NotExist),
+			expectPublic: "user not found",
+			expectError:  "users.Get: user not found: db.Query: network unreachable",
+		},
+		{
+			name:         "op without msg",
+			err:          E(errDB, Op("users.Get")),
+			expectPublic: "network unreachable",
+			expectError:  "users.Get: db.Query: network unreachable",
+		},
+		{
+			name:         "empty msg",
+			err:          E(New(""), Op("users.Get"), NotExist),
+			expectPublic: "item does not exist",
+			expectError:  "users.Get",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err.(*Error)
+			if got := err.PublicError(); tt.expectPublic != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectPublic, got)
+			}
+
+			if got := err.Error(); tt.expectError != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectError, got)
+			}
+
+			for _, op := range err.Ops() {
+				if strings.Contains(err.Msg(), op) || strings.Contains(err.PublicError(), op) {
+					t.Errorf("\nop %s should not be part of the public output: %s", op, err.PublicError())
+				}
+			}
+		})
+	}
+}
+
+func TestReparent(t *testing.T) {
+	errOld := New("network unreachable")
+	chain := E(E(errOld, "io error", IO), "user not found", NotExist).(*Error)
+	errRoot := New("record missing")
+
+	err := Reparent(errRoot, chain)
+	if Cause(err) != errRoot {
+		t.Errorf("\nexpected: %v\n     got: %v", errRoot, Cause(err))
+	}
+
+	if expect := "user not found: io error: record missing"; err.Error() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, err.Error())
+	}
+
+	if !IsKind(err, NotExist) {
+		t.Errorf("\nexpected: %s\n     got: %s", NotExist, KindOf(err))
+	}
+
+	if Cause(chain) != errOld {
+		t.Errorf("\noriginal modified\nexpected: %v\n     got: %v", errOld, Cause(chain))
+	}
+
+	if got := Reparent(nil, chain); got != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", got)
+	}
+}
+
+func TestE_DedupMessages(t *testing.T) {
+	defer func(v bool) { DedupMessages = v }(DedupMessages)
+
+	tc := []struct {
+		name   string
+		dedup  bool
+		err    func() error
+		expect string
+	}{
+		{
+			name:   "disabled",
+			dedup:  false,
+			err:    func() error { return E(E(New("bar"), "foo"), "foo") },
+			expect: "foo: foo: bar",
+		},
+		{
+			name:   "enabled",
+			dedup:  true,
+			err:    func() error { return E(E(New("bar"), "foo"), "foo") },
+			expect: "foo: bar",
+		},
+		{
+			name:   "enabled plain cause",
+			dedup:  true,
+			err:    func() error { return E(New("foo: bar"), "foo") },
+			expect: "foo: bar",
+		},
+		{
+			name:   "enabled different msg",
+			dedup:  true,
+			err:    func() error { return E(E(New("bar"), "foo"), "baz") },
+			expect: "baz: foo: bar",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			DedupMessages = tt.dedup
+			if got := tt.err().Error(); tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestToStd(t *testing.T) {
+	errRoot := New("network unreachable")
+	errIO := E(errRoot, Op("db.Query"), "io error", IO)
+	err := E(E(errIO, Internal), "user not found", NotExist, MetaData{"user": "bob"})
+
+	std := ToStd(err)
+	if _, ok := std.(*Error); ok {
+		t.Error("invalid error, should not be of type errors.Error")
+	}
+
+	if std.Error() != err.Error() {
+		t.Errorf("\nexpected: %s\n     got: %s", err.Error(), std.Error())
+	}
+
+	expect := []string{
+		"user not found: db.Query: io error: network unreachable",
+		"db.Query: io error: network unreachable",
+		"network unreachable",
+	}
+
+	layer := std
+	for _, msg := range expect {
+		if layer == nil {
+			t.Errorf("\nexpected: %s\n     got: nil", msg)
+			return
+		}
+
+		if _, ok := layer.(*Error); ok {
+			t.Errorf("\nlayer %s should not be of type errors.Error", msg)
+		}
+
+		if layer.Error() != msg {
+			t.Errorf("\nexpected: %s\n     got: %s", msg, layer.Error())
+		}
+		layer = stderrors.Unwrap(layer)
+	}
+
+	if !stderrors.Is(std, errRoot) {
+		t.Errorf("\nexpected %v to be reachable from %v", errRoot, std)
+	}
+}
+
+func TestExternalize(t *testing.T) {
+	errDecrypt := E(New("key 0xdeadbeef rejected"), "invalid key", Decrypt, MetaData{"key_id": "k1"})
+	errDB := E(New("dial tcp 10.0.0.1:5432"), "querying users", IO)
+	errNotExist := E(New("foo"), "user not found", NotExist)
+
+	tc := []struct {
+		name      string
+		err       error
+		expectMsg string
+		expectKnd Kind
+		same      bool
+	}{
+		{
+			name:      "decrypt",
+			err:       errDecrypt,
+			expectMsg: "internal error",
+			expectKnd: Internal,
+		},
+		{
+			name:      "server fault",
+			err:       errDB,
+			expectMsg: "internal error",
+			expectKnd: Internal,
+		},
+		{
+			name:      "std error",
+			err:       fmt.Errorf("secret"),
+			expectMsg: "internal error",
+			expectKnd: Internal,
+		},
+		{
+			name:      "client fault",
+			err:       errNotExist,
+			expectMsg: "user not found",
+			expectKnd: NotExist,
+			same:      true,
+		},
+		{
+			name:      "client fault behind a std error",
+			err:       E(fmt.Errorf("repo: %w", errNotExist), "loading profile"),
+			expectMsg: "loading profile",
+			expectKnd: NotExist,
+			same:      true,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			ext := Externalize(tt.err)
+			e, ok := ext.(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if tt.same {
+				if ext != tt.err {
+					t.Errorf("\nexpected: %v\n     got: %v", tt.err, ext)
+				}
+				return
+			}
+
+			if e.Kind != tt.expectKnd {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKnd, e.Kind)
+			}
+
+			if msg := e.Msg(); msg != tt.expectMsg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+
+			b, jerr := json.Marshal(e)
+			if jerr != nil {
+				t.Error(jerr)
+				return
+			}
+
+			if expect := `{"type":"internal error","error":"internal error","code":7}`; string(b) != expect {
+				t.Errorf("\nexpected: %s\n     got: %s", expect, string(b))
+			}
+
+			if e.Cause() != tt.err {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.err, e.Cause())
+			}
+		})
+	}
+
+	if err := Externalize(nil); err != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", err)
+	}
+}
+
+func TestLookupMeta(t *testing.T) {
+	err := E(
+		E(
+			E(New("foo"), "db", MetaData{"table": "users", "retry": false}),
+			"repo", MetaData{"retry": true},
+		),
+		"service",
+	)
+	e := err.(*Error)
+
+	tc := []struct {
+		key         string
+		expectOk    bool
+		expectValue interface{}
+	}{
+		{key: "table", expectOk: true, expectValue: "users"},
+		{key: "retry", expectOk: true, expectValue: true},
+		{key: "missing"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.key, func(t *testing.T) {
+			v, ok := e.LookupMeta(tt.key)
+			if ok != tt.expectOk {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expectOk, ok)
+			}
+
+			if v != tt.expectValue {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expectValue, v)
+			}
+
+			if has := e.HasMeta(tt.key); has != tt.expectOk {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expectOk, has)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tc := []struct {
+		a, b   Kind
+		expect Kind
+	}{
+		{a: Unknown, b: NotExist, expect: NotExist},
+		{a: NotExist, b: Unknown, expect: NotExist},
+		{a: NotExist, b: IO, expect: IO},
+		{a: Internal, b: Invalid, expect: Internal},
+		{a: Invalid, b: NotExist, expect: Invalid},
+		{a: IO, b: Transient, expect: IO},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.a.name()+"_"+tt.b.name(), func(t *testing.T) {
+			if got := Combine(tt.a, tt.b); got != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestMergeChains(t *testing.T) {
+	errCache := stderrors.New("cache down")
+	errDB := stderrors.New("db down")
+	a := E(E(errCache, "get session", Transient), "cache")
+	b := E(E(errDB, "query user", NotExist), "db")
+
+	err := MergeChains(a, b)
+	e, ok := err.(*Error)
+	if !ok {
+		t.Error("invalid error, should be of type errors.Error")
+		return
+	}
+
+	if e.Kind != Transient {
+		t.Errorf("\nexpected: %s\n     got: %s", Transient, e.Kind)
+	}
+
+	for _, target := range []error{errCache, errDB} {
+		if !stderrors.Is(err, target) {
+			t.Errorf("%v should be reachable from %v", target, err)
+		}
+	}
+
+	expect := "cache: get session: cache down; db: query user: db down"
+	if msg := err.Error(); msg != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, msg)
+	}
+
+	if got := MergeChains(nil, b); got != b {
+		t.Errorf("\nexpected: %v\n     got: %v", b, got)
+	}
+}
+
+func TestEKV(t *testing.T) {
+	tc := []struct {
+		name       string
+		kv         []interface{}
+		expectMeta MetaData
+	}{
+		{
+			name: "no pairs",
+		},
+		{
+			name:       "even",
+			kv:         []interface{}{"user_id", 42, "table", "users"},
+			expectMeta: MetaData{"user_id": 42, "table": "users"},
+		},
+		{
+			name:       "odd",
+			kv:         []interface{}{"user_id", 42, "table"},
+			expectMeta: MetaData{"user_id": 42, "table": MissingValue},
+		},
+		{
+			name:       "non string key",
+			kv:         []interface{}{1, "one"},
+			expectMeta: MetaData{"1": "one"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := EKV(New("foo"), NotExist, "user not found", tt.kv...).(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if e.Kind != NotExist {
+				t.Errorf("\nexpected: %s\n     got: %s", NotExist, e.Kind)
+			}
+
+			if msg := e.Msg(); msg != "user not found" {
+				t.Errorf("\nexpected: %s\n     got: %s", "user not found", msg)
+			}
+
+			if !reflect.DeepEqual(tt.expectMeta, e.Meta) {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expectMeta, e.Meta)
+			}
+		})
+	}
+}
+
+func TestEffectiveStatusCode(t *testing.T) {
+	tc := []struct {
+		name         string
+		err          error
+		expectStatus int
+		expectTop    int
+	}{
+		{
+			name:         "unknown top layer",
+			err:          &Error{s: "handler", cause: E(New("foo"), "user not found", NotExist)},
+			expectStatus: http.StatusNotFound,
+			expectTop:    http.StatusInternalServerError,
+		},
+		{
+			name:         "several unknown layers",
+			err:          &Error{s: "handler", cause: &Error{s: "repo", cause: Kinded(Duplicated, "user exists")}},
+			expectStatus: http.StatusConflict,
+			expectTop:    http.StatusInternalServerError,
+		},
+		{
+			name:         "known top layer",
+			err:          E(E(New("foo"), NotExist), "invalid user", Invalid),
+			expectStatus: http.StatusBadRequest,
+			expectTop:    http.StatusBadRequest,
+		},
+		{
+			name:         "no kind",
+			err:          E(New("foo"), "handler"),
+			expectStatus: http.StatusInternalServerError,
+			expectTop:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.err.(*Error)
+			if got := e.EffectiveStatusCode(); got != tt.expectStatus {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectStatus, got)
+			}
+
+			if got := e.StatusCode(); got != tt.expectTop {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectTop, got)
+			}
+		})
+	}
+}
+
+func TestKindResolution(t *testing.T) {
+	defer func(r Resolution) { KindResolution = r }(KindResolution)
+
+	// handler (Unknown) -> service (Invalid) -> repo (NotExist) -> std error
+	err := &Error{
+		s: "handler",
+		cause: E(
+			E(New("foo"), "repo", NotExist),
+			"service", Invalid,
+		),
+	}
+
+	tc := []struct {
+		name         string
+		strategy     Resolution
+		expectKind   Kind
+		expectStatus int
+	}{
+		{name: "outermost", strategy: Outermost, expectKind: Unknown, expectStatus: http.StatusInternalServerError},
+		{name: "innermost", strategy: Innermost, expectKind: NotExist, expectStatus: http.StatusNotFound},
+		{name: "first known", strategy: FirstKnown, expectKind: Invalid, expectStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			KindResolution = tt.strategy
+
+			if got := KindOf(err); got != tt.expectKind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, got)
+			}
+
+			if !IsKind(err, tt.expectKind) {
+				t.Errorf("IsKind should report %s", tt.expectKind)
+			}
+
+			if got := err.EffectiveStatusCode(); got != tt.expectStatus {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectStatus, got)
+			}
+		})
+	}
+}
+
+func TestMarshalList(t *testing.T) {
+	tc := []struct {
+		name   string
+		errs   []error
+		expect string
+	}{
+		{
+			name:   "empty",
+			expect: `{"errors":[]}`,
+		},
+		{
+			name: "mixed",
+			errs: []error{
+				E(New("foo"), "user not found", NotExist),
+				nil,
+				stderrors.New("boom"),
+				E(New("foo"), "invalid email", Invalid, MetaData{"field": "email"}),
+			},
+			expect: `{"errors":[` +
+				`{"type":"item does not exist","error":"user not found","code":5},` +
+				`{"type":"Unknown error","error":"boom","code":0},` +
+				`{"detail":{"field":"email"},"type":"invalid operation","error":"invalid email","code":1}` +
+				`]}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := MarshalList(tt.errs)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
+
+func TestKind_Title(t *testing.T) {
+	tc := []struct {
+		kind   Kind
+		expect string
+	}{
+		{kind: Unknown, expect: "Internal Server Error"},
+		{kind: Invalid, expect: "Bad Request"},
+		{kind: Permission, expect: "Unauthorized"},
+		{kind: IO, expect: "Internal Server Error"},
+		{kind: Duplicated, expect: "Conflict"},
+		{kind: NotExist, expect: "Not Found"},
+		{kind: Private, expect: "Unauthorized"},
+		{kind: Internal, expect: "Internal Server Error"},
+		{kind: Decrypt, expect: "Bad Request"},
+		{kind: Unmarshal, expect: "Bad Request"},
+		{kind: Transient, expect: "Service Unavailable"},
+		{kind: Unsupported, expect: "Unsupported Media Type"},
+		{kind: NotAcceptable, expect: "Not Acceptable"},
+		{kind: Timeout, expect: "Request Timeout"},
+		{kind: Unimplemented, expect: "Not Implemented"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.kind.name(), func(t *testing.T) {
+			if got := tt.kind.Title(); got != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestWrapIf(t *testing.T) {
+	errFoo := stderrors.New("foo")
+
+	tc := []struct {
+		name       string
+		cond       bool
+		err        error
+		expectKind Kind
+		expectMsg  string
+		same       bool
+	}{
+		{name: "wrapped", cond: true, err: errFoo, expectKind: Transient, expectMsg: "fetching user: foo"},
+		{name: "unchanged", cond: false, err: errFoo, same: true},
+		{name: "nil wrapped", cond: true, same: true},
+		{name: "nil unchanged", cond: false, same: true},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WrapIf(tt.cond, tt.err, Transient, "fetching user")
+			if tt.same {
+				if err != tt.err {
+					t.Errorf("\nexpected: %v\n     got: %v", tt.err, err)
+				}
+				return
+			}
+
+			if !IsKind(err, tt.expectKind) {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, KindOf(err))
+			}
+
+			if msg := err.Error(); msg != tt.expectMsg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+		})
+	}
+}
+
+func TestRegisterKind(t *testing.T) {
+	defer func(kinds []customKindInfo) { customKinds = kinds }(customKinds)
+
+	quota := RegisterKind("QuotaExceeded", http.StatusTooManyRequests)
+	gone := RegisterKind("Gone", http.StatusGone)
+
+	if quota < firstCustomKind || gone != quota+1 {
+		t.Errorf("invalid kinds allocated: %d, %d", quota, gone)
+	}
+
+	tc := []struct {
+		kind         Kind
+		expectString string
+		expectCode   string
+		expectStatus int
+	}{
+		{kind: quota, expectString: "QuotaExceeded", expectCode: "QUOTA_EXCEEDED", expectStatus: http.StatusTooManyRequests},
+		{kind: gone, expectString: "Gone", expectCode: "GONE", expectStatus: http.StatusGone},
+		{kind: gone + 1, expectString: "unknown error kind", expectCode: "KIND(66)", expectStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.expectString, func(t *testing.T) {
+			if got := tt.kind.String(); got != tt.expectString {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectString, got)
+			}
+
+			if got := tt.kind.Code(); got != tt.expectCode {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectCode, got)
+			}
+
+			if got := E(New("foo"), tt.kind).(*Error).StatusCode(); got != tt.expectStatus {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectStatus, got)
+			}
+		})
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	root := E(New("no rows"), "user not found", NotExist).(*Error)
+
+	deep := error(root)
+	for i := 0; i < 5; i++ {
+		deep = fmt.Errorf("layer %d: %w", i, deep)
+	}
+
+	tc := []struct {
+		name   string
+		err    error
+		expect *Error
+	}{
+		{
+			name:   "foreign wrappers",
+			err:    fmt.Errorf("handler: %w", fmt.Errorf("service: %w", root)),
+			expect: root,
+		},
+		{
+			name:   "deeply nested",
+			err:    deep,
+			expect: root,
+		},
+		{
+			name:   "multi error",
+			err:    &MultiError{Errors: []error{New("foo"), fmt.Errorf("cache: %w", root)}},
+			expect: root,
+		},
+		{
+			name: "not found",
+			err:  fmt.Errorf("handler: %w", New("foo")),
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var target *Error
+			ok := stderrors.As(tt.err, &target)
+			if ok != (tt.expect != nil) {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect != nil, ok)
+			}
+
+			if target != tt.expect {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, target)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tc := []struct {
+		name         string
+		err          error
+		expectKind   Kind
+		expectStatus int
+		expectMsg    string
+		expectError  string
+	}{
+		{
+			name: "client fault",
+			err: E(
+				E(New("select * from users"), "query", IO, MetaData{"host": "db1"}),
+				"user not found", NotExist, Op("users.Get"), MetaData{"user_id": 42},
+			),
+			expectKind:   NotExist,
+			expectStatus: http.StatusNotFound,
+			expectMsg:    "user not found",
+			expectError:  "user not found: item does not exist",
+		},
+		{
+			name:         "client fault without msg",
+			err:          E(New("sql: secret table users"), NotExist),
+			expectKind:   NotExist,
+			expectStatus: http.StatusNotFound,
+			expectMsg:    "item does not exist",
+			expectError:  "item does not exist",
+		},
+		{
+			name:         "client fault behind a std error",
+			err:          fmt.Errorf("handler: %w", E(New("sql: secret"), "user not found", NotExist)),
+			expectKind:   NotExist,
+			expectStatus: http.StatusNotFound,
+			expectMsg:    "user not found",
+			expectError:  "user not found: item does not exist",
+		},
+		{
+			name:         "server fault",
+			err:          E(New("dial tcp 10.0.0.1:5432"), "querying users", IO, MetaData{"host": "db1"}),
+			expectKind:   IO,
+			expectStatus: http.StatusInternalServerError,
+			expectMsg:    "I/O error",
+			expectError:  "I/O error",
+		},
+		{
+			name:         "std error",
+			err:          fmt.Errorf("secret"),
+			expectKind:   Unknown,
+			expectStatus: http.StatusInternalServerError,
+			expectMsg:    "Unknown error",
+			expectError:  "Unknown error",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := Sanitize(tt.err).(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if e.Kind != tt.expectKind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, e.Kind)
+			}
+
+			if got := e.StatusCode(); got != tt.expectStatus {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectStatus, got)
+			}
+
+			if got := e.Msg(); got != tt.expectMsg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, got)
+			}
+
+			if got := e.Error(); got != tt.expectError {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectError, got)
+			}
+
+			if e.Meta != nil || e.Op != "" {
+				t.Errorf("\nexpected no metadata nor op\n     got: %v %s", e.Meta, e.Op)
+			}
+
+			if _, ok := e.Cause().(*Error); ok {
+				t.Errorf("\nexpected a leaf cause\n     got: %v", e.Cause())
+			}
+		})
+	}
+
+	if err := Sanitize(nil); err != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", err)
+	}
+}
+
+func TestUnmarshalUnprocessable(t *testing.T) {
+	defer func(v bool) { UnmarshalUnprocessable = v }(UnmarshalUnprocessable)
+
+	tc := []struct {
+		name            string
+		enabled         bool
+		expectUnmarshal int
+		expectKind      Kind
+	}{
+		{name: "disabled", expectUnmarshal: http.StatusBadRequest, expectKind: Invalid},
+		{name: "enabled", enabled: true, expectUnmarshal: http.StatusUnprocessableEntity, expectKind: Unmarshal},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			UnmarshalUnprocessable = tt.enabled
+
+			if got := Unmarshal.StatusCode(); got != tt.expectUnmarshal {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectUnmarshal, got)
+			}
+
+			if got := Invalid.StatusCode(); got != http.StatusBadRequest {
+				t.Errorf("\nexpected: %d\n     got: %d", http.StatusBadRequest, got)
+			}
+
+			if got := KindFromStatusCode(http.StatusUnprocessableEntity); got != tt.expectKind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, got)
+			}
+		})
+	}
+}
+
+type closer struct {
+	err error
+}
+
+func (c closer) Close() error {
+	return c.err
+}
+
+func TestCloseWith(t *testing.T) {
+	errClose := stderrors.New("close failed")
+	errRead := stderrors.New("read failed")
+
+	read := func(readErr, closeErr error) (err error) {
+		defer CloseWith(&err, closer{closeErr}, IO, "closing file")
+		return readErr
+	}
+
+	tc := []struct {
+		name         string
+		readErr      error
+		closeErr     error
+		expectMsg    string
+		expectKind   Kind
+		expectStatus int
+	}{
+		{name: "no errors"},
+		{
+			name:         "close error",
+			closeErr:     errClose,
+			expectMsg:    "closing file: close failed",
+			expectKind:   IO,
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "read error",
+			readErr:      E(errRead, "reading", Invalid),
+			expectMsg:    "reading: read failed",
+			expectKind:   Invalid,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "both errors",
+			readErr:      E(errRead, "reading", NotExist),
+			closeErr:     errClose,
+			expectMsg:    "reading: read failed; closing file: close failed",
+			expectKind:   NotExist,
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:         "both errors with a std read error",
+			readErr:      fmt.Errorf("reading: %w", errRead),
+			closeErr:     errClose,
+			expectMsg:    "reading: read failed; closing file: close failed",
+			expectKind:   Unknown,
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := read(tt.readErr, tt.closeErr)
+			if tt.expectMsg == "" {
+				if err != nil {
+					t.Errorf("\nexpected: nil\n     got: %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.expectMsg {
+				t.Errorf("\nexpected: %s\n     got: %v", tt.expectMsg, err)
+				return
+			}
+
+			if got := KindOf(err); got != tt.expectKind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, got)
+			}
+
+			if got := ToStatus(err); got != tt.expectStatus {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectStatus, got)
+			}
+
+			if tt.closeErr != nil && !stderrors.Is(err, errClose) {
+				t.Errorf("the close error should be kept, got: %v", err)
+			}
+
+			if tt.readErr != nil && !stderrors.Is(err, errRead) {
+				t.Errorf("the read error should be kept, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetMarshalHook(t *testing.T) {
+	defer SetMarshalHook(nil)
+
+	SetMarshalHook(func(e *Error) *Error {
+		if _, ok := e.Meta["password"]; ok {
+			e.Meta["password"] = "[redacted]"
+		}
+		return e
+	})
+
+	err := E(New("foo"), "invalid credentials", Permission, MetaData{"user": "bob", "password": "hunter2"}).(*Error)
+
+	b, jerr := json.Marshal(err)
+	if jerr != nil {
+		t.Error(jerr)
+		return
+	}
+
+	expect := `{"detail":{"password":"[redacted]","user":"bob"},"type":"permission denied","error":"invalid credentials","code":2}`
+	if string(b) != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, string(b))
+	}
+
+	if err.Meta["password"] != "hunter2" {
+		t.Errorf("\nexpected: the original error untouched\n     got: %v", err.Meta)
+	}
+
+	SetMarshalHook(nil)
+	b, _ = json.Marshal(err)
+	if !strings.Contains(string(b), "hunter2") {
+		t.Errorf("\nexpected: the hook removed\n     got: %s", string(b))
+	}
+}
+
+func TestKind_SeverityRank(t *testing.T) {
+	tc := []struct {
+		lower, higher Kind
+	}{
+		{lower: Invalid, higher: NotExist},
+		{lower: NotExist, higher: Permission},
+		{lower: Permission, higher: Transient},
+		{lower: Transient, higher: IO},
+		{lower: IO, higher: Internal},
+		{lower: Unknown, higher: Internal},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.lower.name()+"_"+tt.higher.name(), func(t *testing.T) {
+			if tt.lower.SeverityRank() >= tt.higher.SeverityRank() {
+				t.Errorf("%s should rank lower than %s", tt.lower.name(), tt.higher.name())
+			}
+		})
+	}
+
+	errs := []error{
+		E(New("foo"), NotExist),
+		E(New("foo"), Internal),
+		E(New("foo"), Invalid),
+		E(New("foo"), Timeout),
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return KindOf(errs[i]).SeverityRank() > KindOf(errs[j]).SeverityRank()
+	})
+
+	expect := []Kind{Internal, Timeout, NotExist, Invalid}
+	for i, err := range errs {
+		if got := KindOf(err); got != expect[i] {
+			t.Errorf("\nexpected: %s\n     got: %s", expect[i], got)
+		}
+	}
+}
+
+func TestMostSevere(t *testing.T) {
+	errNotExist := E(New("foo"), NotExist)
+	errTimeout := E(New("foo"), Timeout)
+	errTimeout2 := E(New("bar"), Timeout)
+	errInternal := E(New("foo"), Internal)
+
+	tc := []struct {
+		name   string
+		errs   []error
+		expect error
+	}{
+		{name: "mixed", errs: []error{errNotExist, errInternal, errTimeout}, expect: errInternal},
+		{name: "tie", errs: []error{errNotExist, errTimeout, errTimeout2}, expect: errTimeout},
+		{name: "with nil", errs: []error{nil, errNotExist, nil}, expect: errNotExist},
+		{name: "all nil", errs: []error{nil, nil}},
+		{name: "empty"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MostSevere(tt.errs...); got != tt.expect {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON_RawMessage(t *testing.T) {
+	detail := json.RawMessage(`{"field":"email","errors":["required"]}`)
+
+	tc := []struct {
+		name   string
+		meta   MetaData
+		expect string
+	}{
+		{
+			name:   "value",
+			meta:   MetaData{"validation": detail},
+			expect: `{"detail":{"validation":{"field":"email","errors":["required"]}},"type":"invalid operation","error":"invalid input","code":1}`,
+		},
+		{
+			name:   "pointer",
+			meta:   MetaData{"validation": &detail},
+			expect: `{"detail":{"validation":{"field":"email","errors":["required"]}},"type":"invalid operation","error":"invalid input","code":1}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(E(New("foo"), "invalid input", Invalid, tt.meta))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
+
+func TestWithMetaReplaced(t *testing.T) {
+	orig := E(New("foo"), "user not found", NotExist, MetaData{"user": "bob", "table": "users"}).(*Error)
+	m := MetaData{"request_id": "abc"}
+
+	e := orig.WithMetaReplaced(m)
+	if expect := (MetaData{"request_id": "abc"}); !reflect.DeepEqual(expect, e.Meta) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, e.Meta)
+	}
+
+	if e.Kind != NotExist || e.Msg() != "user not found" {
+		t.Errorf("\nexpected: the kind and msg kept\n     got: %s %s", e.Kind, e.Msg())
+	}
+
+	if expect := (MetaData{"user": "bob", "table": "users"}); !reflect.DeepEqual(expect, orig.Meta) {
+		t.Errorf("\nexpected: the original untouched %v\n     got: %v", expect, orig.Meta)
+	}
+
+	m["request_id"] = "changed"
+	if e.Meta["request_id"] != "abc" {
+		t.Errorf("\nexpected: a copy of m\n     got: %v", e.Meta)
+	}
+
+	if e := orig.WithMetaReplaced(nil); e.Meta != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", e.Meta)
+	}
+}
+
+func TestKeyCase(t *testing.T) {
+	defer func(c KeyCasing, key string) { KeyCase, MessageKey = c, key }(KeyCase, MessageKey)
+
+	err := E(New("foo"), "user not found", NotExist, MetaData{"user_id": 42}, Op("users.Get"))
+
+	tc := []struct {
+		name       string
+		casing     KeyCasing
+		messageKey string
+		expect     string
+	}{
+		{
+			name:       "default",
+			casing:     KeyCase,
+			messageKey: "errorMessage",
+			expect:     `{"detail":{"user_id":42},"type":"item does not exist","error_message":"user not found","code":5,"op":"users.Get"}`,
+		},
+		{
+			name:       "as is",
+			casing:     AsIs,
+			messageKey: "errorMessage",
+			expect:     `{"detail":{"user_id":42},"type":"item does not exist","errorMessage":"user not found","code":5,"op":"users.Get"}`,
+		},
+		{
+			name:       "as is with a capitalized key",
+			casing:     AsIs,
+			messageKey: "Message",
+			expect:     `{"detail":{"user_id":42},"type":"item does not exist","Message":"user not found","code":5,"op":"users.Get"}`,
+		},
+		{
+			name:       "snake from camel",
+			casing:     SnakeCase,
+			messageKey: "errorMessage",
+			expect:     `{"detail":{"user_id":42},"type":"item does not exist","error_message":"user not found","code":5,"op":"users.Get"}`,
+		},
+		{
+			name:       "snake from snake",
+			casing:     SnakeCase,
+			messageKey: "error_message",
+			expect:     `{"detail":{"user_id":42},"type":"item does not exist","error_message":"user not found","code":5,"op":"users.Get"}`,
+		},
+		{
+			name:       "camel from snake",
+			casing:     CamelCase,
+			messageKey: "error_message",
+			expect:     `{"detail":{"user_id":42},"type":"item does not exist","errorMessage":"user not found","code":5,"op":"users.Get"}`,
+		},
+		{
+			name:       "camel from camel",
+			casing:     CamelCase,
+			messageKey: "errorMessage",
+			expect:     `{"detail":{"user_id":42},"type":"item does not exist","errorMessage":"user not found","code":5,"op":"users.Get"}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			KeyCase, MessageKey = tt.casing, tt.messageKey
+
+			b, jerr := json.Marshal(err)
+			if jerr != nil {
+				t.Error(jerr)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
+
+func TestKeyCasing_apply(t *testing.T) {
+	tc := []struct {
+		key         string
+		expectSnake string
+		expectCamel string
+	}{
+		{key: "code", expectSnake: "code", expectCamel: "code"},
+		{key: "code_name", expectSnake: "code_name", expectCamel: "codeName"},
+		{key: "codeName", expectSnake: "code_name", expectCamel: "codeName"},
+		{key: "error-message-text", expectSnake: "error_message_text", expectCamel: "errorMessageText"},
+		{key: "userID", expectSnake: "user_id", expectCamel: "userID"},
+		{key: "HTTPStatus", expectSnake: "http_status", expectCamel: "HTTPStatus"},
+		{key: "user_id", expectSnake: "user_id", expectCamel: "userId"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := AsIs.apply(tt.key); got != tt.key {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.key, got)
+			}
+
+			if got := SnakeCase.apply(tt.key); got != tt.expectSnake {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectSnake, got)
+			}
+
+			if got := CamelCase.apply(tt.key); got != tt.expectCamel {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectCamel, got)
+			}
+		})
+	}
+}
+
+func TestWithDuration(t *testing.T) {
+	orig := E(New("foo"), "query timed out", Timeout).(*Error)
+	e := orig.WithDuration(1500 * time.Millisecond)
+
+	if e.Meta["duration_ms"] != int64(1500) {
+		t.Errorf("\nexpected: 1500\n     got: %v", e.Meta["duration_ms"])
+	}
+
+	tc := []struct {
+		name     string
+		err      *Error
+		expect   time.Duration
+		expectOk bool
+	}{
+		{name: "set", err: e, expect: 1500 * time.Millisecond, expectOk: true},
+		{name: "wrapped", err: E(e, "handler").(*Error), expect: 1500 * time.Millisecond, expectOk: true},
+		{name: "decoded", err: orig.WithMeta(MetaData{"duration_ms": float64(20)}), expect: 20 * time.Millisecond, expectOk: true},
+		{name: "not set", err: orig},
+		{name: "invalid", err: orig.WithMeta(MetaData{"duration_ms": "slow"})},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := tt.err.Duration()
+			if ok != tt.expectOk || d != tt.expect {
+				t.Errorf("\nexpected: %s %v\n     got: %s %v", tt.expect, tt.expectOk, d, ok)
+			}
+		})
+	}
+}
+
+func TestMapKinds(t *testing.T) {
+	root := &Error{s: "query", cause: New("foo")}
+	mid := E(root, "user not found", NotExist).(*Error)
+	orig := &Error{s: "handler", cause: mid}
+
+	e := orig.MapKinds(func(k Kind) Kind {
+		if k == Unknown {
+			return Internal
+		}
+		return k
+	})
+
+	var kinds []Kind
+	for _, layer := range Filter(e, func(*Error) bool { return true }) {
+		kinds = append(kinds, layer.Kind)
+	}
+
+	expect := []Kind{Internal, NotExist, Internal}
+	if !reflect.DeepEqual(expect, kinds) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, kinds)
+	}
+
+	if orig.Kind != Unknown || root.Kind != Unknown {
+		t.Errorf("\nexpected: the original untouched\n     got: %s %s", orig.Kind, root.Kind)
+	}
+
+	var nilErr *Error
+	if nilErr.MapKinds(func(k Kind) Kind { return k }) != nil {
+		t.Error("expected nil")
+	}
+}
+
+func TestEmitEmptyDetail(t *testing.T) {
+	defer func(v bool) { EmitEmptyDetail = v }(EmitEmptyDetail)
+
+	tc := []struct {
+		name   string
+		emit   bool
+		meta   MetaData
+		expect string
+	}{
+		{name: "nil", meta: nil, expect: `{"type":"item does not exist","error":"foo","code":5}`},
+		{name: "empty", meta: MetaData{}, expect: `{"type":"item does not exist","error":"foo","code":5}`},
+		{name: "populated", meta: MetaData{"id": 1}, expect: `{"detail":{"id":1},"type":"item does not exist","error":"foo","code":5}`},
+		{name: "emit nil", emit: true, meta: nil, expect: `{"type":"item does not exist","error":"foo","code":5}`},
+		{name: "emit empty", emit: true, meta: MetaData{}, expect: `{"detail":{},"type":"item does not exist","error":"foo","code":5}`},
+		{name: "emit populated", emit: true, meta: MetaData{"id": 1}, expect: `{"detail":{"id":1},"type":"item does not exist","error":"foo","code":5}`},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			EmitEmptyDetail = tt.emit
+
+			b, err := json.Marshal(&Error{Kind: NotExist, cause: New("foo"), Meta: tt.meta})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
+
+func TestSerializedSize(t *testing.T) {
+	minimal := E(New("foo"), NotExist).(*Error)
+	heavy := minimal.WithMeta(MetaData{"body": strings.Repeat("x", 1024), "user_id": 42})
+
+	tc := []struct {
+		name string
+		err  *Error
+	}{
+		{name: "minimal", err: minimal},
+		{name: "heavy", err: heavy},
+	}
+
+	sizes := make([]int, len(tc))
+	for i, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := tt.err.SerializedSize()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			b, _ := json.Marshal(tt.err)
+			if size != len(b) {
+				t.Errorf("\nexpected: %d\n     got: %d", len(b), size)
+			}
+			sizes[i] = size
+		})
+	}
+
+	if sizes[1] <= sizes[0]+1024 {
+		t.Errorf("the heavy error should be bigger: %d vs %d", sizes[1], sizes[0])
+	}
+
+	bad := minimal.WithMeta(MetaData{"ch": make(chan int)})
+	if _, err := bad.SerializedSize(); err == nil {
+		t.Error("expected an error for metadata that can't be serialized")
+	}
+}
+
+func TestCollapseKinds(t *testing.T) {
+	root := E(New("connection refused"), "dial", IO)
+	orig := E(
+		E(
+			E(root, "query", Internal, MetaData{"table": "users"}),
+			"repo", Op("users.Repo"),
+		),
+		"service", MetaData{"user": "bob"},
+	).(*Error)
+
+	e := orig.CollapseKinds()
+
+	layers := Filter(e, func(*Error) bool { return true })
+	if len(layers) != 2 {
+		t.Errorf("\nexpected: 2 layers\n     got: %d", len(layers))
+		return
+	}
+
+	top := layers[0]
+	if top.Kind != Internal || top.s != "service: repo: query" || top.Op != "users.Repo" {
+		t.Errorf("\nexpected: [Internal] users.Repo service: repo: query\n     got: [%s] %s %s", top.Kind.name(), top.Op, top.s)
+	}
+
+	if expect := (MetaData{"user": "bob", "table": "users"}); !reflect.DeepEqual(expect, top.Meta) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, top.Meta)
+	}
+
+	if layers[1].Kind != IO {
+		t.Errorf("\nexpected: %s\n     got: %s", IO, layers[1].Kind)
+	}
+
+	if expect := "users.Repo: service: repo: query: dial: connection refused"; e.Error() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, e.Error())
+	}
+
+	if got := len(Filter(orig, func(*Error) bool { return true })); got != 4 {
+		t.Errorf("\nexpected: the original untouched\n     got: %d layers", got)
+	}
+}
+
+func TestCollapseKinds_userMessage(t *testing.T) {
+	u := E(New("x"), "a", Invalid).(*Error).WithUserMessage("Oops: try later")
+
+	tc := []struct {
+		name string
+		err  *Error
+	}{
+		{name: "user msg over the same kind", err: u},
+		{name: "op only layer over the user msg", err: E(u, Op("svc.Do")).(*Error)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.err.CollapseKinds()
+			if expect := "Oops: try later"; e.Msg() != expect {
+				t.Errorf("\nexpected: %s\n     got: %s", expect, e.Msg())
+			}
+
+			if e.Error() != tt.err.Error() {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.err.Error(), e.Error())
+			}
+		})
+	}
+}
+
+func TestZero(t *testing.T) {
+	if msg := Zero.Error(); msg != "" {
+		t.Errorf("\nexpected: empty\n     got: %s", msg)
+	}
+
+	if kind := KindOf(Zero); kind != Unknown {
+		t.Errorf("\nexpected: %s\n     got: %s", Unknown, kind)
+	}
+
+	if IsNil(Zero) {
+		t.Error("Zero should not be nil")
+	}
+
+	if _, err := json.Marshal(Zero); err != nil {
+		t.Error(err)
+	}
+
+	var err error = Zero
+	if err != Zero {
+		t.Error("Zero should compare by identity")
+	}
+
+	if !stderrors.Is(err, Zero) {
+		t.Error("Zero should match itself")
+	}
+
+	if stderrors.Is(E(New("x"), "y"), Zero) {
+		t.Error("Zero should not match other Unknown errors")
+	}
+}
+
+func TestAtLevel(t *testing.T) {
+	err := E(
+		E(New("network unreachable"), "io error", IO, MetaData{"host": "db"}),
+		"user not found", NotExist, MetaData{"user": "bob"},
+	).(*Error)
+
+	tc := []struct {
+		name    string
+		err     *Error
+		verbose bool
+		expect  string
+	}{
+		{
+			name:   "info",
+			err:    err,
+			expect: "[NotExist] user not found",
+		},
+		{
+			name:    "debug",
+			err:     err,
+			verbose: true,
+			expect:  "[NotExist] user not found {user=bob} -> [IO] io error {host=db} -> network unreachable",
+		},
+		{
+			name:   "info without msg",
+			err:    &Error{Kind: Internal},
+			expect: "[Internal]",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.AtLevel(tt.verbose); got != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestGo(t *testing.T) {
+	errFoo := stderrors.New("foo")
+
+	tc := []struct {
+		name       string
+		fn         func() error
+		expect     error
+		expectKind Kind
+		expectMsg  string
+	}{
+		{name: "error", fn: func() error { return errFoo }, expect: errFoo},
+		{name: "nil", fn: func() error { return nil }},
+		{
+			name:       "panic",
+			fn:         func() error { panic("boom") },
+			expectKind: Internal,
+			expectMsg:  "panic recovered: boom",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			select {
+			case err = <-Go(tt.fn):
+			case <-time.After(time.Second):
+				t.Error("timed out waiting for the result")
+				return
+			}
+
+			if tt.expectMsg == "" {
+				if err != tt.expect {
+					t.Errorf("\nexpected: %v\n     got: %v", tt.expect, err)
+				}
+				return
+			}
+
+			if !IsKind(err, tt.expectKind) {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, KindOf(err))
+			}
+
+			if err.Error() != tt.expectMsg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, err.Error())
+			}
+		})
+	}
+}
+
+type reason int
+
+func (r reason) String() string {
+	return [...]string{"unknown reason", "quota exceeded", "account locked"}[r]
+}
+
+func TestE_Stringer(t *testing.T) {
+	tc := []struct {
+		name       string
+		args       []interface{}
+		expectMsg  string
+		expectKind Kind
+	}{
+		{
+			name:       "stringer",
+			args:       []interface{}{reason(1), Permission},
+			expectMsg:  "quota exceeded",
+			expectKind: Permission,
+		},
+		{
+			name:       "string takes precedence",
+			args:       []interface{}{"plain msg", reason(2)},
+			expectMsg:  "plain msg",
+			expectKind: Unknown,
+		},
+		{
+			name:       "string takes precedence regardless of order",
+			args:       []interface{}{reason(2), "plain msg"},
+			expectMsg:  "plain msg",
+			expectKind: Unknown,
+		},
+		{
+			name:       "kind is not a msg",
+			args:       []interface{}{NotExist},
+			expectMsg:  "foo",
+			expectKind: NotExist,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			e := E(New("foo"), tt.args...).(*Error)
+			if msg := e.Msg(); msg != tt.expectMsg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+
+			if e.Kind != tt.expectKind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, e.Kind)
+			}
+		})
+	}
+}
+
+func TestMarkLogged(t *testing.T) {
+	orig := E(New("foo"), "user not found", NotExist).(*Error)
+	logged := orig.MarkLogged()
+
+	tc := []struct {
+		name   string
+		err    *Error
+		expect bool
+	}{
+		{name: "not marked", err: orig},
+		{name: "marked", err: logged, expect: true},
+		{name: "wrapped", err: E(logged, "handler").(*Error), expect: true},
+		{name: "wrapped by a std error", err: E(fmt.Errorf("handler: %w", logged), "api").(*Error), expect: true},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.WasLogged(); got != tt.expect {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, got)
+			}
+		})
+	}
+
+	if logged == orig || logged.Error() != orig.Error() {
+		t.Errorf("\nexpected: a copy of %v\n     got: %v", orig, logged)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	orig := E(
+		E(New("no rows"), "query", IO),
+		"user not found", NotExist, Op("users.Get"), MetaData{"user": "bob"},
+	).(*Error)
+
+	body, err := json.Marshal(orig)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	e, err := ParseJSON(body)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if e.Kind != NotExist || e.Op != "users.Get" || e.Msg() != "user not found" {
+		t.Errorf("\nexpected: [NotExist] users.Get user not found\n     got: [%s] %s %s", e.Kind.name(), e.Op, e.Msg())
+	}
+
+	if expect := (MetaData{"user": "bob"}); !reflect.DeepEqual(expect, e.Meta) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, e.Meta)
+	}
+
+	if e.StatusCode() != http.StatusNotFound {
+		t.Errorf("\nexpected: %d\n     got: %d", http.StatusNotFound, e.StatusCode())
+	}
+
+	if expect := "users.Get: user not found"; e.Error() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, e.Error())
+	}
+
+	if stderrors.Is(E(New("foo"), "other user", NotExist), e) {
+		t.Error("a parsed error should not match every local error of its kind")
+	}
+
+	if !stderrors.Is(e, &Error{Kind: NotExist}) {
+		t.Error("a parsed error should match a sentinel of its kind")
+	}
+
+	again, err := json.Marshal(e)
+	if err != nil || string(again) != string(body) {
+		t.Errorf("\nexpected: %s\n     got: %s", body, again)
+	}
+
+	var decoded struct {
+		Err *Error `json:"err"`
+	}
+	if err := json.Unmarshal([]byte(`{"err":`+string(body)+`}`), &decoded); err != nil || decoded.Err.Kind != NotExist {
+		t.Errorf("\nexpected: the error decoded via UnmarshalJSON\n     got: %v %v", decoded.Err, err)
+	}
+
+	for _, data := range []string{`not json`, `{"code":"NOT_EXIST"}`} {
+		if _, err := ParseJSON([]byte(data)); !IsKind(err, Unmarshal) {
+			t.Errorf("\nexpected: %s error for %s\n     got: %v", Unmarshal, data, err)
+		}
+	}
+}
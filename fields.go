@@ -0,0 +1,94 @@
+package errors
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// FieldErrors holds the validation errors of a request keyed by field,
+// the values are human friendly messages
+type FieldErrors map[string]string
+
+// NestedFieldErrors makes FieldErrors serialize nested paths as nested
+// objects, as returned by Nested, instead of flat with the paths as keys
+var NestedFieldErrors bool
+
+// Error format the output, joining the errors of all the fields
+// sorted by field
+func (f FieldErrors) Error() string {
+	keys := f.keys()
+
+	msgs := make([]string, len(keys))
+	for i, k := range keys {
+		msgs[i] = k + ": " + f[k]
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+// ErrorKind returns Invalid, it implements Kinder
+func (f FieldErrors) ErrorKind() Kind {
+	return Invalid
+}
+
+// MarshalJSON serializes the field errors flat, as a map of path to msg,
+// or nested when NestedFieldErrors is enabled
+func (f FieldErrors) MarshalJSON() ([]byte, error) {
+	if NestedFieldErrors {
+		return json.Marshal(f.Nested())
+	}
+
+	return json.Marshal(map[string]string(f))
+}
+
+// Nested returns the field errors as nested objects, the paths are split
+// on dots and brackets, e.g. "address.zip" and "items[0].name" give
+//
+//	{"address": {"zip": "required"}, "items": {"0": {"name": "required"}}}
+//
+// If a path is also the prefix of a deeper one, the deeper one wins
+func (f FieldErrors) Nested() map[string]interface{} {
+	nested := map[string]interface{}{}
+	for _, path := range f.keys() {
+		segments := splitPath(path)
+		if len(segments) == 0 {
+			continue
+		}
+
+		node := nested
+		for _, segment := range segments[:len(segments)-1] {
+			child, ok := node[segment].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[segment] = child
+			}
+			node = child
+		}
+
+		last := segments[len(segments)-1]
+		if _, ok := node[last].(map[string]interface{}); !ok {
+			node[last] = f[path]
+		}
+	}
+
+	return nested
+}
+
+// keys returns the paths of the field errors sorted
+func (f FieldErrors) keys() []string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// splitPath splits a field path like "items[0].name" into its segments
+func splitPath(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool {
+		return r == '.' || r == '[' || r == ']'
+	})
+}
@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFieldErrors(t *testing.T) {
+	fields := FieldErrors{
+		"name":  "is required",
+		"email": "must be a valid email",
+	}
+
+	expect := "email: must be a valid email, name: is required"
+	if got := fields.Error(); expect != got {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, got)
+	}
+
+	err := E(fields, "validation failed")
+	if !IsKind(err, Invalid) {
+		t.Errorf("\nexpected: %s\n     got: %s", Invalid, KindOf(err))
+	}
+}
+
+func TestFieldErrors_Nested(t *testing.T) {
+	fields := FieldErrors{
+		"name":          "is required",
+		"address":       "is invalid",
+		"address.zip":   "is required",
+		"address.city":  "is too long",
+		"items[0].name": "is required",
+	}
+
+	expect := map[string]interface{}{
+		"name": "is required",
+		"address": map[string]interface{}{
+			"zip":  "is required",
+			"city": "is too long",
+		},
+		"items": map[string]interface{}{
+			"0": map[string]interface{}{"name": "is required"},
+		},
+	}
+
+	if got := fields.Nested(); !reflect.DeepEqual(expect, got) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, got)
+	}
+}
+
+func TestFieldErrors_MarshalJSON(t *testing.T) {
+	defer func(v bool) { NestedFieldErrors = v }(NestedFieldErrors)
+
+	fields := FieldErrors{
+		"name":        "is required",
+		"address.zip": "is required",
+	}
+
+	tc := []struct {
+		name   string
+		nested bool
+		expect string
+	}{
+		{name: "flat", expect: `{"address.zip":"is required","name":"is required"}`},
+		{name: "nested", nested: true, expect: `{"address":{"zip":"is required"},"name":"is required"}`},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			NestedFieldErrors = tt.nested
+
+			b, err := json.Marshal(fields)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
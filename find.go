@@ -0,0 +1,26 @@
+package errors
+
+import stderrors "errors"
+
+// Find returns the first error in the tree of err assignable to T, the
+// tree is walked by errors.As, so every cause of multi cause errors like
+// MergeChains is considered. Chains only implementing Cause are walked
+// via Cause as a fallback. It is a type safe version of errors.As, e.g.
+//
+//	if e, ok := Find[*Error](err); ok {
+//		...
+//	}
+func Find[T error](err error) (T, bool) {
+	var t T
+	if stderrors.As(err, &t) {
+		return t, true
+	}
+
+	for ; !IsNil(err); err = next(err) {
+		if t, ok := err.(T); ok {
+			return t, true
+		}
+	}
+
+	return t, false
+}
@@ -0,0 +1,45 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+)
+
+// causer only exposes its cause via Cause, as pkg/errors used to do
+type causer struct{ cause error }
+
+func (c causer) Error() string { return "causer: " + c.cause.Error() }
+func (c causer) Cause() error  { return c.cause }
+
+func TestFind(t *testing.T) {
+	custom := &customErr{msg: "boom"}
+	inner := E(custom, "query", IO).(*Error)
+	err := fmt.Errorf("handler: %w", E(inner, "user not found", NotExist))
+
+	e, ok := Find[*Error](err)
+	if !ok || e.Msg() != "user not found" {
+		t.Errorf("\nexpected: user not found\n     got: %v", e)
+	}
+
+	c, ok := Find[*customErr](err)
+	if !ok || c != custom {
+		t.Errorf("\nexpected: %v\n     got: %v", custom, c)
+	}
+
+	if _, ok := Find[*MultiError](err); ok {
+		t.Error("no *MultiError should be found")
+	}
+
+	merged := MergeChains(New("a"), custom)
+	if c, ok := Find[*customErr](merged); !ok || c != custom {
+		t.Errorf("\nexpected: %v\n     got: %v", custom, c)
+	}
+
+	if c, ok := Find[*customErr](causer{custom}); !ok || c != custom {
+		t.Errorf("\nexpected: %v\n     got: %v", custom, c)
+	}
+
+	if _, ok := Find[*Error](nil); ok {
+		t.Error("no *Error should be found in a nil error")
+	}
+}
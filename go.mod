@@ -1,3 +1,3 @@
 module github.com/mishudark/errors
 
-go 1.16
+go 1.20
@@ -0,0 +1,91 @@
+// Package grpc maps the kinds of github.com/mishudark/errors to gRPC
+// codes and back
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/mishudark/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code transform kind to a gRPC codes.Code
+func Code(k errors.Kind) codes.Code {
+	switch k {
+	case errors.Invalid,
+		errors.Decrypt,
+		errors.Unmarshal,
+		errors.Unsupported,
+		errors.NotAcceptable:
+		return codes.InvalidArgument
+	case errors.Permission,
+		errors.Private:
+		return codes.PermissionDenied
+	case errors.Transient:
+		return codes.Unavailable
+	case errors.NotExist:
+		return codes.NotFound
+	case errors.Duplicated:
+		return codes.AlreadyExists
+	case errors.Timeout:
+		return codes.DeadlineExceeded
+	case errors.Unimplemented:
+		return codes.Unimplemented
+	case errors.Unknown:
+		return codes.Unknown
+	case errors.Internal:
+	case errors.IO:
+	}
+
+	return codes.Internal
+}
+
+// CodeOf returns a gRPC codes.Code based on the kind of err, as
+// reported by errors.KindOf
+func CodeOf(err error) codes.Code {
+	return Code(errors.KindOf(err))
+}
+
+// StatusWithDetails returns a gRPC status with the code from Code and
+// the msg from Msg. An errdetails.ErrorInfo is attached as detail, with
+// the kind Code as reason and the metadata, formatted with fmt.Sprint,
+// as its metadata
+func StatusWithDetails(e *errors.Error) (*status.Status, error) {
+	info := &errdetails.ErrorInfo{
+		Reason:   e.Kind.Code(),
+		Metadata: make(map[string]string, len(e.Meta)),
+	}
+
+	for k, v := range e.Meta {
+		info.Metadata[k] = fmt.Sprint(v)
+	}
+
+	return status.New(Code(e.Kind), e.Msg()).WithDetails(info)
+}
+
+// KindFromCode transform a gRPC codes.Code into a kind, it is useful
+// to translate errors received from an upstream service. Codes without
+// an equivalent kind are reported as Internal
+func KindFromCode(code codes.Code) errors.Kind {
+	switch code {
+	case codes.NotFound:
+		return errors.NotExist
+	case codes.PermissionDenied,
+		codes.Unauthenticated:
+		return errors.Permission
+	case codes.AlreadyExists:
+		return errors.Duplicated
+	case codes.Unavailable:
+		return errors.Transient
+	case codes.DeadlineExceeded:
+		return errors.Timeout
+	case codes.InvalidArgument:
+		return errors.Invalid
+	case codes.Unimplemented:
+		return errors.Unimplemented
+	}
+
+	return errors.Internal
+}
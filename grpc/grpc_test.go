@@ -0,0 +1,103 @@
+package grpc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mishudark/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCode(t *testing.T) {
+	tc := []struct {
+		kind   errors.Kind
+		expect codes.Code
+	}{
+		{kind: errors.Unknown, expect: codes.Unknown},
+		{kind: errors.Invalid, expect: codes.InvalidArgument},
+		{kind: errors.Permission, expect: codes.PermissionDenied},
+		{kind: errors.IO, expect: codes.Internal},
+		{kind: errors.Duplicated, expect: codes.AlreadyExists},
+		{kind: errors.NotExist, expect: codes.NotFound},
+		{kind: errors.Internal, expect: codes.Internal},
+		{kind: errors.Transient, expect: codes.Unavailable},
+		{kind: errors.Timeout, expect: codes.DeadlineExceeded},
+		{kind: errors.Unimplemented, expect: codes.Unimplemented},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			got := CodeOf(errors.E(errors.New("foo"), tt.kind))
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestKindFromCode(t *testing.T) {
+	tc := []struct {
+		code   codes.Code
+		expect errors.Kind
+	}{
+		{code: codes.NotFound, expect: errors.NotExist},
+		{code: codes.PermissionDenied, expect: errors.Permission},
+		{code: codes.Unauthenticated, expect: errors.Permission},
+		{code: codes.AlreadyExists, expect: errors.Duplicated},
+		{code: codes.Unavailable, expect: errors.Transient},
+		{code: codes.DeadlineExceeded, expect: errors.Timeout},
+		{code: codes.InvalidArgument, expect: errors.Invalid},
+		{code: codes.Unimplemented, expect: errors.Unimplemented},
+		{code: codes.DataLoss, expect: errors.Internal},
+		{code: codes.Unknown, expect: errors.Internal},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			got := KindFromCode(tt.code)
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestStatusWithDetails(t *testing.T) {
+	err := errors.E(errors.New("foo"), "user not found", errors.NotExist, errors.MetaData{"user_id": 42, "table": "users"})
+
+	st, serr := StatusWithDetails(err.(*errors.Error))
+	if serr != nil {
+		t.Error(serr)
+		return
+	}
+
+	if st.Code() != codes.NotFound {
+		t.Errorf("\nexpected: %s\n     got: %s", codes.NotFound, st.Code())
+	}
+
+	if expect := "user not found"; st.Message() != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, st.Message())
+	}
+
+	details := st.Details()
+	if len(details) != 1 {
+		t.Errorf("\nexpected: 1 detail\n     got: %d", len(details))
+		return
+	}
+
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	if !ok {
+		t.Errorf("invalid detail, should be of type *errdetails.ErrorInfo, got: %T", details[0])
+		return
+	}
+
+	if expect := errors.NotExist.Code(); info.Reason != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, info.Reason)
+	}
+
+	expect := map[string]string{"user_id": "42", "table": "users"}
+	if !reflect.DeepEqual(expect, info.Metadata) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, info.Metadata)
+	}
+}
@@ -0,0 +1,399 @@
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode"
+)
+
+// StatusCoder is implemented by errors that know which http.StatusCode
+// they should be reported with, *Error is one of them but errors from
+// other libraries can advertise it as well
+type StatusCoder interface {
+	StatusCode() int
+}
+
+var _ StatusCoder = (*Error)(nil)
+
+// ToStatus returns the http.StatusCode for err, the first error in the
+// chain implementing StatusCoder is used, otherwise
+// http.StatusInternalServerError is returned. An *Error with an Unknown
+// kind only adds context, so it is skipped in favor of deeper errors
+func ToStatus(err error) int {
+	for ; !IsNil(err); err = next(err) {
+		if e, ok := err.(*Error); ok && e.Kind == Unknown {
+			continue
+		}
+
+		if s, ok := err.(StatusCoder); ok {
+			return s.StatusCode()
+		}
+	}
+
+	return http.StatusInternalServerError
+}
+
+// SameStatus reports whether a and b map to the same http.StatusCode,
+// as reported by ToStatus, so errors without a status are 500
+func SameStatus(a, b error) bool {
+	return ToStatus(a) == ToStatus(b)
+}
+
+// AuthScheme is the WWW-Authenticate header set by WriteHTTP on 401
+// responses that don't set one already, an empty scheme disables it
+var AuthScheme = "Bearer"
+
+// WriteHTTP writes err as a json response, the status is taken from
+// ToStatus and the headers of the chain, see WithHeader, are set. Errors
+// that aren't an *Error are serialized as if they were wrapped by E.
+// The kind in the body always matches the status, see responseKind.
+// Nothing is written if err is nil
+func WriteHTTP(w http.ResponseWriter, err error) error {
+	if IsNil(err) {
+		return nil
+	}
+
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{cause: err, Kind: KindOf(err)}
+	}
+
+	status := ToStatus(err)
+	if kind := responseKind(e, status); kind != e.Kind {
+		c := *e
+		c.Kind = kind
+		e = &c
+	}
+
+	b, jerr := json.Marshal(e)
+	if jerr != nil {
+		return jerr
+	}
+
+	for k, v := range e.Header() {
+		w.Header()[k] = v
+	}
+
+	if status == http.StatusUnauthorized && AuthScheme != "" && w.Header().Get("WWW-Authenticate") == "" {
+		w.Header().Set("WWW-Authenticate", AuthScheme)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	return err
+}
+
+// responseKind returns the kind to report along with status, as given
+// by ToStatus, so a body never contradicts the status of its response.
+// The kind of e is kept if it maps to status, e.g. a custom kind, it is
+// given by KindFromStatusCode otherwise, e.g. for an Unknown layer over
+// a foreign StatusCoder
+func responseKind(e *Error, status int) Kind {
+	if e.Kind.StatusCode() == status {
+		return e.Kind
+	}
+
+	return KindFromStatusCode(status)
+}
+
+// WithHeader returns a copy of the error with the response header key
+// set to value, it is applied by WriteHTTP, e.g. WWW-Authenticate for
+// a Permission error. The original error is left untouched
+func (e *Error) WithHeader(key, value string) *Error {
+	c := *e
+	c.header = e.header.Clone()
+	if c.header == nil {
+		c.header = http.Header{}
+	}
+
+	c.header.Set(key, value)
+	return &c
+}
+
+// Header returns the headers set via WithHeader in every *Error of the
+// chain, the outer layers take precedence
+func (e *Error) Header() http.Header {
+	header := http.Header{}
+	for err := error(e); !IsNil(err); err = next(err) {
+		layer, ok := err.(*Error)
+		if !ok {
+			continue
+		}
+
+		for k, v := range layer.header {
+			if _, ok := header[k]; !ok {
+				header[k] = append([]string(nil), v...)
+			}
+		}
+	}
+
+	return header
+}
+
+// TraceHeader is the request header read by WithTraceFromRequest
+var TraceHeader = "X-Trace-Id"
+
+// WithTraceFromRequest stores the value of the TraceHeader of r in the
+// metadata of err under the key "trace_id", it ties the errors of an
+// API to its traces. err is returned unchanged if r doesn't carry the
+// header, and like E, nil is returned if err is nil
+func WithTraceFromRequest(r *http.Request, err error) error {
+	if IsNil(err) {
+		return nil
+	}
+
+	if r == nil {
+		return err
+	}
+
+	trace := r.Header.Get(TraceHeader)
+	if trace == "" {
+		return err
+	}
+
+	meta := MetaData{"trace_id": trace}
+	if e, ok := err.(*Error); ok {
+		return e.WithMeta(meta)
+	}
+
+	return E(err, meta)
+}
+
+// Headers is an alias of Header, it implements the Headerer interface
+// of go-kit, so its DefaultErrorEncoder sets the headers of the error
+func (e *Error) Headers() http.Header {
+	return e.Header()
+}
+
+// RespondHTTP applies the usual policy of a central handler: client
+// faults are written as is via WriteHTTP, server faults are passed to
+// logger and the client only receives a generic Internal error, so
+// internal details aren't leaked. The fault is given by ToStatus, as in
+// WriteHTTP. Nothing is written if err is nil
+func RespondHTTP(w http.ResponseWriter, err error, logger func(error)) {
+	if IsNil(err) {
+		return
+	}
+
+	if status := ToStatus(err); status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		WriteHTTP(w, err)
+		return
+	}
+
+	if logger != nil {
+		logger(err)
+	}
+	WriteHTTP(w, E(New(Internal.String()), Internal))
+}
+
+// ProblemTypeBase is the base of the URIs returned by Kind.ProblemType
+var ProblemTypeBase = "https://errors.example/kind/"
+
+// ProblemType returns the RFC 7807 problem type URI of the kind, that is
+// ProblemTypeBase followed by the kind in kebab case, e.g.
+// "https://errors.example/kind/not-exist"
+func (k Kind) ProblemType() string {
+	return ProblemTypeBase + k.words('-', unicode.ToLower)
+}
+
+// problemMembers are the members defined by RFC 7807, metadata with
+// the same keys is not added as extension
+var problemMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+}
+
+// ProblemJSON serializes the error as an RFC 7807 problem+json body,
+// with the members type, title, status and detail, status is given by
+// ToStatus, title by the Kind.Title of responseKind and detail by
+// PublicError. The metadata is added as extension members sorted by key
+func (e *Error) ProblemJSON() ([]byte, error) {
+	status := ToStatus(e)
+	kind := responseKind(e, status)
+	fields := []field{
+		{"type", kind.ProblemType()},
+		{"title", kind.Title()},
+		{"status", status},
+		{"detail", e.PublicError()},
+	}
+
+	meta := truncatedMeta(e.Meta)
+	for _, k := range meta.keys() {
+		if problemMembers[k] {
+			continue
+		}
+
+		fields = append(fields, field{k, meta[k]})
+	}
+
+	return marshalObject(fields)
+}
+
+// TemplateData returns the data to render an error page, e.g. via
+// html/template, with the keys Title, Status, Message and Code given by
+// Kind.Title, ToStatus, PublicError and Kind.Code, where the kind is the
+// one of responseKind. Internal details,
+// like the cause chain, ops and metadata, are deliberately left out
+func (e *Error) TemplateData() map[string]interface{} {
+	status := ToStatus(e)
+	kind := responseKind(e, status)
+	return map[string]interface{}{
+		"Title":   kind.Title(),
+		"Status":  status,
+		"Message": e.PublicError(),
+		"Code":    kind.Code(),
+	}
+}
+
+// KindFromStatusCode transform an http.StatusCode into a kind, it is
+// the inverse of Kind.StatusCode. Success codes are reported as Unknown.
+// http.StatusUnprocessableEntity is reported as Unmarshal if
+// UnmarshalUnprocessable is enabled, otherwise as Invalid
+func KindFromStatusCode(status int) Kind {
+	switch status {
+	case http.StatusUnprocessableEntity:
+		if UnmarshalUnprocessable {
+			return Unmarshal
+		}
+		return Invalid
+	case http.StatusBadRequest:
+		return Invalid
+	case http.StatusUnauthorized,
+		http.StatusForbidden:
+		return Permission
+	case http.StatusNotFound:
+		return NotExist
+	case http.StatusConflict:
+		return Duplicated
+	case http.StatusUnsupportedMediaType:
+		return Unsupported
+	case http.StatusNotAcceptable:
+		return NotAcceptable
+	case http.StatusRequestTimeout,
+		http.StatusGatewayTimeout:
+		return Timeout
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable:
+		return Transient
+	case http.StatusNotImplemented:
+		return Unimplemented
+	}
+
+	switch {
+	case status >= http.StatusInternalServerError:
+		return Internal
+	case status >= http.StatusBadRequest:
+		return Invalid
+	}
+
+	return Unknown
+}
+
+// ResponseBodyLimit is the max number of bytes of the body that
+// FromResponse stores in the metadata, 0 disables it
+var ResponseBodyLimit = 512
+
+// FromResponse builds an error from a non 2xx *http.Response, the kind
+// is taken from KindFromStatusCode and the msg from the status text.
+// The status and at most ResponseBodyLimit bytes of the body are stored
+// in the metadata under the keys "status" and "body".
+// Closing the body is still responsibility of the caller
+func FromResponse(resp *http.Response) error {
+	if resp == nil || resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	status := resp.Status
+	if status == "" {
+		status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	}
+
+	meta := MetaData{"status": resp.StatusCode}
+	if ResponseBodyLimit > 0 && resp.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, int64(ResponseBodyLimit)))
+		if err == nil && len(body) > 0 {
+			meta["body"] = string(body)
+		}
+	}
+
+	return E(New(status), http.StatusText(resp.StatusCode), KindFromStatusCode(resp.StatusCode), meta)
+}
+
+// RetryError builds the error reported after every attempt of a request
+// failed. The kind is taken from the last attempt, if it has none it is
+// Transient. A summary of each attempt, with its "status" and "error",
+// is stored in the metadata under the key "attempts"
+func RetryError(attempts []error) error {
+	var (
+		last    error
+		summary []MetaData
+	)
+
+	for _, err := range attempts {
+		if IsNil(err) {
+			continue
+		}
+
+		status := ToStatus(err)
+		if e, ok := err.(*Error); ok {
+			if v, ok := e.LookupMeta("status"); ok {
+				if code, ok := v.(int); ok {
+					status = code
+				}
+			}
+		}
+
+		summary = append(summary, MetaData{"status": status, "error": err.Error()})
+		last = err
+	}
+
+	if last == nil {
+		return nil
+	}
+
+	kind := KindOf(last)
+	if kind == Unknown {
+		kind = Transient
+	}
+
+	msg := "giving up after " + strconv.Itoa(len(summary)) + " attempts"
+	return E(last, msg, kind, MetaData{"attempts": summary})
+}
+
+// PanicLogger is used by Recover to log the panics, by default they
+// are written to the standard logger
+var PanicLogger = func(err error) {
+	log.Printf("%+v", err)
+}
+
+// Recover is a middleware that recovers the panics of next, converts
+// them via FromRecover and logs them using PanicLogger. The client
+// only receives a generic Internal error to avoid leaking internal info
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			// http.ErrAbortHandler is used by net/http to abort the response
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			PanicLogger(FromRecover(rec))
+			WriteHTTP(w, E(New(Internal.String()), Internal))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
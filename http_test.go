@@ -0,0 +1,739 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// statusCoder is a foreign error type advertising its own status
+type statusCoder struct {
+	status int
+}
+
+func (s statusCoder) Error() string {
+	return "foreign error"
+}
+
+func (s statusCoder) StatusCode() int {
+	return s.status
+}
+
+func TestToStatus(t *testing.T) {
+	tc := []struct {
+		name   string
+		err    error
+		expect int
+	}{
+		{
+			name:   "std error",
+			err:    fmt.Errorf("some error"),
+			expect: http.StatusInternalServerError,
+		},
+		{
+			name:   "errors.Error",
+			err:    E(New("foo"), NotExist),
+			expect: http.StatusNotFound,
+		},
+		{
+			name:   "foreign status coder",
+			err:    statusCoder{status: http.StatusTeapot},
+			expect: http.StatusTeapot,
+		},
+		{
+			name:   "foreign status coder wrapped by std error",
+			err:    fmt.Errorf("wrapped: %w", statusCoder{status: http.StatusTooManyRequests}),
+			expect: http.StatusTooManyRequests,
+		},
+		{
+			name:   "foreign status coder wrapped by E",
+			err:    E(statusCoder{status: http.StatusNotFound}, "loading user"),
+			expect: http.StatusNotFound,
+		},
+		{
+			name:   "kind wins over a deeper status coder",
+			err:    E(statusCoder{status: http.StatusNotFound}, "loading user", Duplicated),
+			expect: http.StatusConflict,
+		},
+		{
+			name:   "unknown error",
+			err:    E(New("foo"), "bar"),
+			expect: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ToStatus(tt.err)
+			if tt.expect != got {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestWriteHTTP(t *testing.T) {
+	tc := []struct {
+		name       string
+		err        error
+		expectCode int
+		expectBody string
+	}{
+		{
+			name:       "errors.Error",
+			err:        E(New("foo"), "user not found", NotExist),
+			expectCode: http.StatusNotFound,
+			expectBody: `{"type":"item does not exist","error":"user not found","code":5}`,
+		},
+		{
+			name:       "foreign status coder",
+			err:        statusCoder{status: http.StatusTeapot},
+			expectCode: http.StatusTeapot,
+			expectBody: `{"type":"invalid operation","error":"foreign error","code":1}`,
+		},
+		{
+			name:       "foreign status coder wrapped by E",
+			err:        E(statusCoder{status: http.StatusNotFound}, "lookup"),
+			expectCode: http.StatusNotFound,
+			expectBody: `{"type":"item does not exist","error":"lookup","code":5}`,
+		},
+		{
+			name:       "std error",
+			err:        fmt.Errorf("some error"),
+			expectCode: http.StatusInternalServerError,
+			expectBody: `{"type":"Unknown error","error":"some error","code":0}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := WriteHTTP(w, tt.err); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if tt.expectCode != w.Code {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectCode, w.Code)
+			}
+
+			if body := w.Body.String(); tt.expectBody != body {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectBody, body)
+			}
+		})
+	}
+}
+
+func TestKindFromStatusCode(t *testing.T) {
+	tc := []struct {
+		status int
+		expect Kind
+	}{
+		{status: http.StatusOK, expect: Unknown},
+		{status: http.StatusBadRequest, expect: Invalid},
+		{status: http.StatusUnauthorized, expect: Permission},
+		{status: http.StatusForbidden, expect: Permission},
+		{status: http.StatusNotFound, expect: NotExist},
+		{status: http.StatusConflict, expect: Duplicated},
+		{status: http.StatusUnsupportedMediaType, expect: Unsupported},
+		{status: http.StatusNotAcceptable, expect: NotAcceptable},
+		{status: http.StatusRequestTimeout, expect: Timeout},
+		{status: http.StatusServiceUnavailable, expect: Transient},
+		{status: http.StatusTeapot, expect: Invalid},
+		{status: http.StatusInternalServerError, expect: Internal},
+		{status: http.StatusNotImplemented, expect: Unimplemented},
+	}
+
+	for _, tt := range tc {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			got := KindFromStatusCode(tt.status)
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestFromResponse(t *testing.T) {
+	defer func(limit int) { ResponseBodyLimit = limit }(ResponseBodyLimit)
+	ResponseBodyLimit = 8
+
+	response := func(status int, body string) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	}
+
+	tc := []struct {
+		name       string
+		resp       *http.Response
+		nilErr     bool
+		expectKind Kind
+		expectMsg  string
+		expectMeta MetaData
+	}{
+		{
+			name:   "success",
+			resp:   response(http.StatusOK, "ok"),
+			nilErr: true,
+		},
+		{
+			name:   "no content",
+			resp:   response(http.StatusNoContent, ""),
+			nilErr: true,
+		},
+		{
+			name:       "not found",
+			resp:       response(http.StatusNotFound, "no user"),
+			expectKind: NotExist,
+			expectMsg:  "Not Found: 404 Not Found",
+			expectMeta: MetaData{"status": http.StatusNotFound, "body": "no user"},
+		},
+		{
+			name:       "body truncated",
+			resp:       response(http.StatusServiceUnavailable, "try again later"),
+			expectKind: Transient,
+			expectMsg:  "Service Unavailable: 503 Service Unavailable",
+			expectMeta: MetaData{"status": http.StatusServiceUnavailable, "body": "try agai"},
+		},
+		{
+			name:       "empty body",
+			resp:       response(http.StatusInternalServerError, ""),
+			expectKind: Internal,
+			expectMsg:  "Internal Server Error: 500 Internal Server Error",
+			expectMeta: MetaData{"status": http.StatusInternalServerError},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromResponse(tt.resp)
+			if tt.nilErr {
+				if err != nil {
+					t.Errorf("\nexpected: nil\n     got: %v", err)
+				}
+				return
+			}
+
+			e, ok := err.(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if tt.expectKind != e.Kind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, e.Kind)
+			}
+
+			if msg := e.Error(); tt.expectMsg != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+
+			if !reflect.DeepEqual(tt.expectMeta, e.Meta) {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expectMeta, e.Meta)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	defer func(logger func(error)) { PanicLogger = logger }(PanicLogger)
+
+	var logged error
+	PanicLogger = func(err error) {
+		logged = err
+	}
+
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("db password is hunter2")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("\nexpected: %d\n     got: %d", http.StatusInternalServerError, w.Code)
+	}
+
+	expect := `{"type":"internal error","error":"internal error","code":7}`
+	if body := w.Body.String(); expect != body {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, body)
+	}
+
+	if logged == nil || !strings.Contains(logged.Error(), "hunter2") {
+		t.Errorf("\nexpected the panic to be logged\n     got: %v", logged)
+	}
+}
+
+func TestRetryError(t *testing.T) {
+	response := func(status int) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}
+	}
+
+	tc := []struct {
+		name           string
+		attempts       []error
+		nilErr         bool
+		expectKind     Kind
+		expectMsg      string
+		expectAttempts []MetaData
+	}{
+		{
+			name:   "no attempts",
+			nilErr: true,
+		},
+		{
+			name: "several attempts",
+			attempts: []error{
+				FromResponse(response(http.StatusServiceUnavailable)),
+				fmt.Errorf("connection reset"),
+				FromResponse(response(http.StatusGatewayTimeout)),
+			},
+			expectKind: Timeout,
+			expectMsg:  "giving up after 3 attempts",
+			expectAttempts: []MetaData{
+				{"status": http.StatusServiceUnavailable, "error": "Service Unavailable: 503 Service Unavailable"},
+				{"status": http.StatusInternalServerError, "error": "connection reset"},
+				{"status": http.StatusGatewayTimeout, "error": "Gateway Timeout: 504 Gateway Timeout"},
+			},
+		},
+		{
+			name:       "unclassified last attempt",
+			attempts:   []error{fmt.Errorf("connection reset"), nil},
+			expectKind: Transient,
+			expectMsg:  "giving up after 1 attempts",
+			expectAttempts: []MetaData{
+				{"status": http.StatusInternalServerError, "error": "connection reset"},
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RetryError(tt.attempts)
+			if tt.nilErr {
+				if err != nil {
+					t.Errorf("\nexpected: nil\n     got: %v", err)
+				}
+				return
+			}
+
+			e, ok := err.(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if tt.expectKind != e.Kind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, e.Kind)
+			}
+
+			if msg := e.Msg(); tt.expectMsg != msg {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectMsg, msg)
+			}
+
+			if !reflect.DeepEqual(tt.expectAttempts, e.Meta["attempts"]) {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expectAttempts, e.Meta["attempts"])
+			}
+		})
+	}
+}
+
+func TestRespondHTTP(t *testing.T) {
+	tc := []struct {
+		name         string
+		err          error
+		expectStatus int
+		expectBody   string
+		expectLogged bool
+	}{
+		{
+			name:         "client fault",
+			err:          E(New("foo"), "user not found", NotExist),
+			expectStatus: http.StatusNotFound,
+			expectBody:   `{"type":"item does not exist","error":"user not found","code":5}`,
+		},
+		{
+			name:         "server fault",
+			err:          E(New("dial tcp 10.0.0.1:5432"), "querying users", IO),
+			expectStatus: http.StatusInternalServerError,
+			expectBody:   `{"type":"internal error","error":"internal error","code":7}`,
+			expectLogged: true,
+		},
+		{
+			name:         "std error",
+			err:          fmt.Errorf("db password is hunter2"),
+			expectStatus: http.StatusInternalServerError,
+			expectBody:   `{"type":"internal error","error":"internal error","code":7}`,
+			expectLogged: true,
+		},
+		{
+			name:         "foreign client fault",
+			err:          statusCoder{status: http.StatusNotFound},
+			expectStatus: http.StatusNotFound,
+			expectBody:   `{"type":"item does not exist","error":"foreign error","code":5}`,
+		},
+		{
+			name:         "foreign client fault wrapped by E",
+			err:          E(statusCoder{status: http.StatusNotFound}, "loading user"),
+			expectStatus: http.StatusNotFound,
+			expectBody:   `{"type":"item does not exist","error":"loading user","code":5}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var logged error
+			w := httptest.NewRecorder()
+			RespondHTTP(w, tt.err, func(err error) {
+				logged = err
+			})
+
+			if w.Code != tt.expectStatus {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectStatus, w.Code)
+			}
+
+			if body := w.Body.String(); tt.expectBody != body {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectBody, body)
+			}
+
+			if tt.expectLogged && logged != tt.err {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.err, logged)
+			}
+
+			if !tt.expectLogged && logged != nil {
+				t.Errorf("\nexpected: nil\n     got: %v", logged)
+			}
+		})
+	}
+}
+
+func TestKind_ProblemType(t *testing.T) {
+	defer func(base string) { ProblemTypeBase = base }(ProblemTypeBase)
+
+	tc := []struct {
+		kind   Kind
+		base   string
+		expect string
+	}{
+		{kind: NotExist, base: ProblemTypeBase, expect: "https://errors.example/kind/not-exist"},
+		{kind: IO, base: ProblemTypeBase, expect: "https://errors.example/kind/io"},
+		{kind: NotAcceptable, base: "urn:problem:", expect: "urn:problem:not-acceptable"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.kind.name(), func(t *testing.T) {
+			ProblemTypeBase = tt.base
+			if got := tt.kind.ProblemType(); got != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	tc := []struct {
+		name   string
+		err    error
+		expect string
+	}{
+		{
+			name:   "without metadata",
+			err:    E(New("foo"), "user not found", NotExist),
+			expect: `{"type":"https://errors.example/kind/not-exist","title":"Not Found","status":404,"detail":"user not found"}`,
+		},
+		{
+			name: "metadata as extensions",
+			err:  E(New("foo"), "invalid email", Invalid, MetaData{"field": "email", "attempt": 2, "status": "ignored"}),
+			expect: `{"type":"https://errors.example/kind/invalid","title":"Bad Request","status":400,` +
+				`"detail":"invalid email","attempt":2,"field":"email"}`,
+		},
+		{
+			name:   "foreign status coder wrapped by E",
+			err:    E(statusCoder{status: http.StatusNotFound}, "lookup"),
+			expect: `{"type":"https://errors.example/kind/not-exist","title":"Not Found","status":404,"detail":"lookup"}`,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.err.(*Error).ProblemJSON()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(b) != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, string(b))
+			}
+		})
+	}
+}
+
+func TestWithHeader(t *testing.T) {
+	base := E(New("foo"), "missing token", Permission).(*Error)
+	inner := base.WithHeader("WWW-Authenticate", `Bearer realm="api"`).WithHeader("X-Request-Id", "abc")
+	err := E(inner.WithHeader("x-request-id", "inner"), "handler").(*Error).WithHeader("X-Request-Id", "outer")
+
+	if len(base.Header()) != 0 {
+		t.Errorf("\nexpected: the original error untouched\n     got: %v", base.Header())
+	}
+
+	if !reflect.DeepEqual(err.Header(), err.Headers()) {
+		t.Errorf("\nexpected: %v\n     got: %v", err.Header(), err.Headers())
+	}
+
+	w := httptest.NewRecorder()
+	if werr := WriteHTTP(w, fmt.Errorf("wrapped: %w", err)); werr != nil {
+		t.Error(werr)
+		return
+	}
+
+	tc := []struct {
+		key    string
+		expect string
+	}{
+		{key: "WWW-Authenticate", expect: `Bearer realm="api"`},
+		{key: "X-Request-Id", expect: "outer"},
+		{key: "Content-Type", expect: "application/json; charset=utf-8"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := w.Header().Get(tt.key); got != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("\nexpected: %d\n     got: %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+// kitErrorEncoder mirrors the DefaultErrorEncoder of go-kit's
+// transport/http, which relies on these interfaces
+func kitErrorEncoder(err error, w http.ResponseWriter) {
+	contentType, body := "text/plain; charset=utf-8", []byte(err.Error())
+	if marshaler, ok := err.(json.Marshaler); ok {
+		if jsonBody, marshalErr := marshaler.MarshalJSON(); marshalErr == nil {
+			contentType, body = "application/json; charset=utf-8", jsonBody
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if headerer, ok := err.(interface{ Headers() http.Header }); ok {
+		for k, values := range headerer.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+
+	code := http.StatusInternalServerError
+	if sc, ok := err.(interface{ StatusCode() int }); ok {
+		code = sc.StatusCode()
+	}
+
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+func TestError_Headers_kitErrorEncoder(t *testing.T) {
+	err := E(New("foo"), "missing token", Permission).(*Error).WithHeader("WWW-Authenticate", `Bearer realm="api"`)
+
+	w := httptest.NewRecorder()
+	kitErrorEncoder(err, w)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("\nexpected: %d\n     got: %d", http.StatusUnauthorized, w.Code)
+	}
+
+	if got := w.Header().Get("WWW-Authenticate"); got != `Bearer realm="api"` {
+		t.Errorf("\nexpected: %s\n     got: %s", `Bearer realm="api"`, got)
+	}
+
+	expect := `{"type":"permission denied","error":"missing token","code":2}`
+	if body := w.Body.String(); body != expect {
+		t.Errorf("\nexpected: %s\n     got: %s", expect, body)
+	}
+}
+
+func TestWriteHTTP_WWWAuthenticate(t *testing.T) {
+	defer func(scheme string) { AuthScheme = scheme }(AuthScheme)
+
+	tc := []struct {
+		name   string
+		scheme string
+		err    error
+		expect string
+	}{
+		{
+			name:   "permission",
+			scheme: "Bearer",
+			err:    E(New("foo"), "missing token", Permission),
+			expect: "Bearer",
+		},
+		{
+			name:   "private",
+			scheme: `Basic realm="api"`,
+			err:    E(New("foo"), Private),
+			expect: `Basic realm="api"`,
+		},
+		{
+			name:   "already present",
+			scheme: "Bearer",
+			err:    E(New("foo"), Permission).(*Error).WithHeader("WWW-Authenticate", `Bearer error="invalid_token"`),
+			expect: `Bearer error="invalid_token"`,
+		},
+		{
+			name:   "disabled",
+			err:    E(New("foo"), Permission),
+			expect: "",
+		},
+		{
+			name:   "not a 401",
+			scheme: "Bearer",
+			err:    E(New("foo"), NotExist),
+			expect: "",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			AuthScheme = tt.scheme
+
+			w := httptest.NewRecorder()
+			if err := WriteHTTP(w, tt.err); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.expect {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestWithTraceFromRequest(t *testing.T) {
+	traced := httptest.NewRequest(http.MethodGet, "/", nil)
+	traced.Header.Set("X-Trace-Id", "abc")
+	untraced := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	tc := []struct {
+		name       string
+		r          *http.Request
+		err        error
+		expectMeta MetaData
+	}{
+		{
+			name:       "*Error",
+			r:          traced,
+			err:        E(New("foo"), NotExist, MetaData{"user": "bob"}),
+			expectMeta: MetaData{"user": "bob", "trace_id": "abc"},
+		},
+		{
+			name:       "std error",
+			r:          traced,
+			err:        New("foo"),
+			expectMeta: MetaData{"trace_id": "abc"},
+		},
+		{
+			name:       "without header",
+			r:          untraced,
+			err:        E(New("foo"), NotExist, MetaData{"user": "bob"}),
+			expectMeta: MetaData{"user": "bob"},
+		},
+		{
+			name:       "nil request",
+			err:        E(New("foo"), NotExist, MetaData{"user": "bob"}),
+			expectMeta: MetaData{"user": "bob"},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := WithTraceFromRequest(tt.r, tt.err).(*Error)
+			if !ok {
+				t.Error("invalid error, should be of type errors.Error")
+				return
+			}
+
+			if !reflect.DeepEqual(tt.expectMeta, e.Meta) {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expectMeta, e.Meta)
+			}
+		})
+	}
+
+	if err := WithTraceFromRequest(traced, nil); err != nil {
+		t.Errorf("\nexpected: nil\n     got: %v", err)
+	}
+}
+
+func TestTemplateData(t *testing.T) {
+	err := E(
+		E(New("select * from users where id = 42"), "query", IO, Op("db.Query")),
+		"user not found", NotExist, MetaData{"user_id": 42},
+	).(*Error)
+
+	expect := map[string]interface{}{
+		"Title":   "Not Found",
+		"Status":  http.StatusNotFound,
+		"Message": "user not found",
+		"Code":    "NOT_EXIST",
+	}
+
+	if got := err.TemplateData(); !reflect.DeepEqual(expect, got) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, got)
+	}
+}
+
+func TestSameStatus(t *testing.T) {
+	tc := []struct {
+		name   string
+		a, b   error
+		expect bool
+	}{
+		{name: "same kind", a: E(New("foo"), NotExist), b: E(New("bar"), NotExist), expect: true},
+		{name: "kinds with the same status", a: E(New("foo"), Invalid), b: E(New("foo"), Decrypt), expect: true},
+		{name: "different status", a: E(New("foo"), NotExist), b: E(New("foo"), Duplicated)},
+		{name: "std error is 500", a: New("foo"), b: E(New("foo"), Internal), expect: true},
+		{name: "wrapped", a: fmt.Errorf("handler: %w", E(New("foo"), NotExist)), b: E(New("foo"), NotExist), expect: true},
+		{name: "foreign status coder", a: statusCoder{status: http.StatusConflict}, b: E(New("foo"), Duplicated), expect: true},
+		{name: "foreign status coder wrapped by E", a: E(statusCoder{status: http.StatusNotFound}, "ctx"), b: E(New("foo"), NotExist), expect: true},
+		{name: "std error vs client fault", a: New("foo"), b: E(New("foo"), Invalid)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameStatus(tt.a, tt.b); got != tt.expect {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestTemplateData_foreignStatusCoder(t *testing.T) {
+	err := E(statusCoder{status: http.StatusNotFound}, "lookup").(*Error)
+
+	expect := map[string]interface{}{
+		"Title":   "Not Found",
+		"Status":  http.StatusNotFound,
+		"Message": "lookup",
+		"Code":    "NOT_EXIST",
+	}
+
+	if got := err.TemplateData(); !reflect.DeepEqual(expect, got) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, got)
+	}
+}
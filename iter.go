@@ -0,0 +1,22 @@
+//go:build go1.23
+// +build go1.23
+
+package errors
+
+import "iter"
+
+// All returns a sequence over the chain of e, from e itself to the
+// innermost error, it allows to range over the chain:
+//
+//	for layer := range e.All() {
+//		...
+//	}
+func (e *Error) All() iter.Seq[error] {
+	return func(yield func(error) bool) {
+		for err := error(e); !IsNil(err); err = next(err) {
+			if !yield(err) {
+				return
+			}
+		}
+	}
+}
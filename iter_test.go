@@ -0,0 +1,38 @@
+//go:build go1.23
+// +build go1.23
+
+package errors
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	root := New("no rows")
+	repo := E(root, "query", IO)
+	service := E(repo, "user not found", NotExist)
+	e := E(service, Op("users.Get")).(*Error)
+
+	var layers []error
+	for layer := range e.All() {
+		layers = append(layers, layer)
+	}
+
+	expect := []error{e, service, repo, root}
+	if !reflect.DeepEqual(expect, layers) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, layers)
+	}
+
+	count := 0
+	for range e.All() {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+
+	if count != 2 {
+		t.Errorf("\nexpected: 2\n     got: %d", count)
+	}
+}
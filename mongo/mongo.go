@@ -0,0 +1,35 @@
+// Package mongo classifies the errors of the mongo-go-driver into the
+// kinds of github.com/mishudark/errors
+package mongo
+
+import (
+	stderrors "errors"
+
+	"github.com/mishudark/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Classify returns the kind of an error reported by the
+// mongo-go-driver:
+//
+//	mongo.ErrNoDocuments    NotExist
+//	duplicate key (11000)   Duplicated
+//	timeout                 Timeout
+//	network error           Transient
+//
+// Unknown is returned for any other error
+func Classify(err error) errors.Kind {
+	switch {
+	case errors.IsNil(err):
+	case stderrors.Is(err, mongo.ErrNoDocuments):
+		return errors.NotExist
+	case mongo.IsDuplicateKeyError(err):
+		return errors.Duplicated
+	case mongo.IsTimeout(err):
+		return errors.Timeout
+	case mongo.IsNetworkError(err):
+		return errors.Transient
+	}
+
+	return errors.Unknown
+}
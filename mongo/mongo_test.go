@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mishudark/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestClassify(t *testing.T) {
+	tc := []struct {
+		name   string
+		err    error
+		expect errors.Kind
+	}{
+		{
+			name:   "no error",
+			err:    nil,
+			expect: errors.Unknown,
+		},
+		{
+			name:   "no documents",
+			err:    fmt.Errorf("finding user: %w", mongo.ErrNoDocuments),
+			expect: errors.NotExist,
+		},
+		{
+			name: "duplicate key write error",
+			err: mongo.WriteException{
+				WriteErrors: mongo.WriteErrors{
+					{Code: 11000, Message: "E11000 duplicate key error collection: db.users index: email_1"},
+				},
+			},
+			expect: errors.Duplicated,
+		},
+		{
+			name:   "duplicate key command error",
+			err:    mongo.CommandError{Code: 11000, Message: "E11000 duplicate key error"},
+			expect: errors.Duplicated,
+		},
+		{
+			name:   "timeout",
+			err:    context.DeadlineExceeded,
+			expect: errors.Timeout,
+		},
+		{
+			name: "other write error",
+			err: mongo.WriteException{
+				WriteErrors: mongo.WriteErrors{
+					{Code: 121, Message: "Document failed validation"},
+				},
+			},
+			expect: errors.Unknown,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+			if tt.expect != got {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expect, got)
+			}
+		})
+	}
+}
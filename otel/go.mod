@@ -0,0 +1,13 @@
+module github.com/mishudark/errors/otel
+
+go 1.25.0
+
+require (
+	github.com/mishudark/errors v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.46.0
+	go.opentelemetry.io/otel/trace v1.46.0
+)
+
+require github.com/cespare/xxhash/v2 v2.3.0 // indirect
+
+replace github.com/mishudark/errors => ../
@@ -0,0 +1,41 @@
+// Package otel records the errors of github.com/mishudark/errors on
+// OpenTelemetry spans
+package otel
+
+import (
+	"net/http"
+
+	"github.com/mishudark/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecordSpanError records err on span along with its kind and code as
+// the attributes "error.kind" and "error.code". Only server faults, that
+// is errors whose errors.ToStatus is 5xx, set the status of the span to
+// Error, client faults leave it Unset since they aren't a failure of the
+// service. It is the same rule used by errors.RespondHTTP, so foreign
+// errors advertising a status are honored. Nothing is recorded if err is
+// nil
+func RecordSpanError(span trace.Span, err error) {
+	if errors.IsNil(err) {
+		return
+	}
+
+	kind := errors.KindOf(err)
+	span.RecordError(err)
+	span.SetAttributes(
+		attribute.String("error.kind", kind.Code()),
+		attribute.Int("error.code", int(kind)),
+	)
+
+	if errors.ToStatus(err) >= http.StatusInternalServerError {
+		msg := err.Error()
+		if e, ok := err.(*errors.Error); ok {
+			msg = e.PublicError()
+		}
+
+		span.SetStatus(codes.Error, msg)
+	}
+}
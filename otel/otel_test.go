@@ -0,0 +1,113 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mishudark/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeSpan records the calls made by RecordSpanError
+type fakeSpan struct {
+	trace.Span
+	status   codes.Code
+	desc     string
+	attrs    map[attribute.Key]attribute.Value
+	recorded []error
+}
+
+func (f *fakeSpan) RecordError(err error, options ...trace.EventOption) {
+	f.recorded = append(f.recorded, err)
+}
+
+func (f *fakeSpan) SetStatus(code codes.Code, description string) {
+	f.status = code
+	f.desc = description
+}
+
+func (f *fakeSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		f.attrs[attr.Key] = attr.Value
+	}
+}
+
+// statusCoder is a foreign error advertising its http status
+type statusCoder struct {
+	status int
+}
+
+func (s statusCoder) Error() string {
+	return "foreign error"
+}
+
+func (s statusCoder) StatusCode() int {
+	return s.status
+}
+
+func TestRecordSpanError(t *testing.T) {
+	tc := []struct {
+		name         string
+		err          error
+		expectStatus codes.Code
+		expectKind   string
+		expectCode   int64
+	}{
+		{
+			name:         "server fault",
+			err:          errors.E(errors.New("network unreachable"), "io error", errors.IO),
+			expectStatus: codes.Error,
+			expectKind:   "IO",
+			expectCode:   int64(errors.IO),
+		},
+		{
+			name:         "client fault",
+			err:          errors.E(errors.New("foo"), "user not found", errors.NotExist),
+			expectStatus: codes.Unset,
+			expectKind:   "NOT_EXIST",
+			expectCode:   int64(errors.NotExist),
+		},
+		{
+			name:         "foreign client fault wrapped by E",
+			err:          errors.E(statusCoder{status: http.StatusNotFound}, "loading user"),
+			expectStatus: codes.Unset,
+			expectKind:   "UNKNOWN",
+			expectCode:   int64(errors.Unknown),
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			span := &fakeSpan{
+				Span:  trace.SpanFromContext(context.Background()),
+				attrs: map[attribute.Key]attribute.Value{},
+			}
+			RecordSpanError(span, tt.err)
+
+			if len(span.recorded) != 1 || span.recorded[0] != tt.err {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.err, span.recorded)
+			}
+
+			if tt.expectStatus != span.status {
+				t.Errorf("\nexpected: %v\n     got: %v", tt.expectStatus, span.status)
+			}
+
+			if kind := span.attrs["error.kind"].AsString(); tt.expectKind != kind {
+				t.Errorf("\nexpected: %s\n     got: %s", tt.expectKind, kind)
+			}
+
+			if code := span.attrs["error.code"].AsInt64(); tt.expectCode != code {
+				t.Errorf("\nexpected: %d\n     got: %d", tt.expectCode, code)
+			}
+		})
+	}
+
+	span := &fakeSpan{Span: trace.SpanFromContext(context.Background())}
+	RecordSpanError(span, nil)
+	if len(span.recorded) != 0 {
+		t.Errorf("\nexpected no errors recorded\n     got: %v", span.recorded)
+	}
+}
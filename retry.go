@@ -0,0 +1,39 @@
+package errors
+
+import (
+	"sync"
+	"time"
+)
+
+// retryPolicy is the policy registered via SetRetryPolicy
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+}
+
+var (
+	retryPoliciesMu sync.RWMutex
+	retryPolicies   = map[Kind]retryPolicy{}
+)
+
+// SetRetryPolicy registers how the errors of kind should be retried, the
+// max number of attempts and the base backoff between them. Setting it
+// again for the same kind replaces the previous policy.
+// It is intended to be called during initialization
+func SetRetryPolicy(kind Kind, maxAttempts int, baseBackoff time.Duration) {
+	retryPoliciesMu.Lock()
+	defer retryPoliciesMu.Unlock()
+
+	retryPolicies[kind] = retryPolicy{maxAttempts: maxAttempts, backoff: baseBackoff}
+}
+
+// RetryPolicy returns the policy registered via SetRetryPolicy for the
+// kind of the chain, as reported by KindOf. ok is false if there is no
+// policy for it
+func (e *Error) RetryPolicy() (maxAttempts int, backoff time.Duration, ok bool) {
+	retryPoliciesMu.RLock()
+	defer retryPoliciesMu.RUnlock()
+
+	p, ok := retryPolicies[KindOf(e)]
+	return p.maxAttempts, p.backoff, ok
+}
@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	defer func() {
+		retryPoliciesMu.Lock()
+		retryPolicies = map[Kind]retryPolicy{}
+		retryPoliciesMu.Unlock()
+	}()
+
+	SetRetryPolicy(Transient, 3, 100*time.Millisecond)
+	SetRetryPolicy(Timeout, 5, time.Second)
+	SetRetryPolicy(Timeout, 2, 500*time.Millisecond)
+
+	tc := []struct {
+		name          string
+		err           *Error
+		expectMax     int
+		expectBackoff time.Duration
+		expectOk      bool
+	}{
+		{
+			name:          "transient",
+			err:           E(New("foo"), Transient).(*Error),
+			expectMax:     3,
+			expectBackoff: 100 * time.Millisecond,
+			expectOk:      true,
+		},
+		{
+			name:          "replaced policy",
+			err:           E(New("foo"), Timeout).(*Error),
+			expectMax:     2,
+			expectBackoff: 500 * time.Millisecond,
+			expectOk:      true,
+		},
+		{
+			name:          "effective kind",
+			err:           &Error{s: "handler", cause: E(New("foo"), Transient)},
+			expectMax:     3,
+			expectBackoff: 100 * time.Millisecond,
+			expectOk:      true,
+		},
+		{
+			name: "without policy",
+			err:  E(New("foo"), NotExist).(*Error),
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, backoff, ok := tt.err.RetryPolicy()
+			if attempts != tt.expectMax || backoff != tt.expectBackoff || ok != tt.expectOk {
+				t.Errorf("\nexpected: %d %s %v\n     got: %d %s %v", tt.expectMax, tt.expectBackoff, tt.expectOk, attempts, backoff, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Package validator converts the errors of go-playground/validator into
+// errors.FieldErrors
+package validator
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/mishudark/errors"
+)
+
+// FromValidation converts validator.ValidationErrors into an Invalid
+// error wrapping FieldErrors, the field errors are also stored in the
+// metadata under the key "fields". Any other error is returned unchanged
+func FromValidation(err error) error {
+	verrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	fields := make(errors.FieldErrors, len(verrs))
+	for _, fe := range verrs {
+		fields[fe.Field()] = validationMsg(fe)
+	}
+
+	return errors.E(fields, "validation failed", errors.Invalid, errors.MetaData{"fields": fields})
+}
+
+// validationMsg returns a human friendly msg for the failed tag
+func validationMsg(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "url":
+		return "must be a valid url"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "len":
+		return "must have a length of " + fe.Param()
+	case "oneof":
+		return "must be one of " + fe.Param()
+	}
+
+	return "failed on the '" + fe.Tag() + "' validation"
+}
@@ -0,0 +1,48 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mishudark/errors"
+)
+
+func TestFromValidation(t *testing.T) {
+	type user struct {
+		Name  string `validate:"required"`
+		Email string `validate:"required,email"`
+		Age   int    `validate:"min=18"`
+	}
+
+	verr := validator.New().Struct(user{Email: "bob", Age: 12})
+	err := FromValidation(verr)
+
+	e, ok := err.(*errors.Error)
+	if !ok {
+		t.Error("invalid error, should be of type errors.Error")
+		return
+	}
+
+	if e.Kind != errors.Invalid {
+		t.Errorf("\nexpected: %s\n     got: %s", errors.Invalid, e.Kind)
+	}
+
+	expect := errors.FieldErrors{
+		"Name":  "is required",
+		"Email": "must be a valid email",
+		"Age":   "must be at least 18",
+	}
+	if got := e.Meta["fields"]; !reflect.DeepEqual(expect, got) {
+		t.Errorf("\nexpected: %v\n     got: %v", expect, got)
+	}
+
+	if msg := e.Msg(); msg != "validation failed" {
+		t.Errorf("\nexpected: %s\n     got: %s", "validation failed", msg)
+	}
+
+	errDummy := errors.New("foo")
+	if got := FromValidation(errDummy); got != errDummy {
+		t.Errorf("\nexpected: %v\n     got: %v", errDummy, got)
+	}
+}